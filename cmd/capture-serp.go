@@ -0,0 +1,103 @@
+package main
+
+import (
+	"flag"
+	"github.com/joho/godotenv"
+	"log"
+	"os"
+	"parser/services/config"
+	"parser/services/proxyx"
+	_ "parser/services/searchBing"
+	_ "parser/services/searchDuckDuckGo"
+	"parser/services/searchEngine"
+	_ "parser/services/searchGoogle"
+	_ "parser/services/searchYandex"
+	"regexp"
+)
+
+// sanitizers replace request ids, keys, nonces and tokens of a live page, so the capture can
+// be committed to a fixture corpus. The markup the parsers depend on is kept as is.
+var sanitizers = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`nonce="[^"]*"`), `nonce="SANITIZED"`},
+	{regexp.MustCompile(`"(reqid|reqId|csrfToken|sk|ei|ved|token)":"[^"]*"`), `"$1":"SANITIZED"`},
+	{regexp.MustCompile(`(name="(?:key|d|k|sk|csrf|token|challenge|rdata)"[^>]*value=")[^"]*"`), `${1}SANITIZED"`},
+	{regexp.MustCompile(`([?&](?:amp;)?(?:key|d|k|sk|u3|rut|ei|ved|p)=)[^&"'\s]*`), `${1}SANITIZED`},
+	{regexp.MustCompile(`\b\d{1,3}\.\d{1,3}\.\d{1,3}\.\d{1,3}\b`), `0.0.0.0`},
+}
+
+func sanitize(html string) string {
+	for _, sanitizer := range sanitizers {
+		html = sanitizer.pattern.ReplaceAllString(html, sanitizer.replacement)
+	}
+
+	return html
+}
+
+// capture-serp saves a sanitized live page of the engine as a parser fixture:
+//
+//	go run cmd/capture-serp.go -engine yandex -text "купить телефон" -out services/searchYandex/testdata/organic_live.html
+//	go test ./services/searchYandex -update
+//
+// The golden file of the capture is written by the -update run and must be reviewed before commit.
+func main() {
+	godotenv.Load()
+
+	engineName := flag.String("engine", "yandex", "search engine")
+	text := flag.String("text", "купить телефон", "search query")
+	lr := flag.String("lr", "213", "search region")
+	page := flag.Int("page", 0, "page number")
+	withSession := flag.Bool("session", true, "generate a session first, without it the engine often returns its captcha page")
+	proxyStr := flag.String("proxy", "", "proxy of the requests (protocol://[user:pass@]host:port)")
+	out := flag.String("out", "", "fixture file")
+	flag.Parse()
+
+	if *out == "" {
+		log.Fatal("-out is required")
+	}
+
+	engine, err := searchEngine.Get(*engineName)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var proxy *proxyx.TProxy
+
+	if *proxyStr != "" {
+		proxyStruct, err := proxyx.StrToStruct(*proxyStr)
+
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		proxy = &proxyStruct
+	}
+
+	var session *searchEngine.Session
+
+	if *withSession {
+		generated, _, err := engine.GenerateSession(config.SessionProbeText, *lr, proxy, nil)
+
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		session = &generated
+	}
+
+	pageUrl := engine.GetSearchPageUrl(*text, *lr, *page)
+	resp, err := engine.Fetch(pageUrl, session, proxy)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := os.WriteFile(*out, []byte(sanitize(resp.Html)), 0644); err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("[INFO] Saved %v (status: %v, blocked: %v, %v organic item(s))", *out, resp.Status, engine.IsBlocked(resp), len(engine.ParsePage(resp.Html).Items))
+}
@@ -1,76 +1,210 @@
 package main
 
 import (
+	"bytes"
+	"encoding/csv"
+	"flag"
 	"fmt"
-	"net/http"
-	"net/url"
+	"github.com/joho/godotenv"
+	"log"
+	"parser/services/config"
 	"parser/services/httpRequest"
-	"strings"
+	"parser/services/proxyx"
+	_ "parser/services/searchBing"
+	_ "parser/services/searchDuckDuckGo"
+	"parser/services/searchEngine"
+	_ "parser/services/searchGoogle"
+	_ "parser/services/searchYandex"
+	"parser/services/storage"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"sync"
 	"time"
 )
 
-type TReport struct {
-	proxy  string
-	status int
-}
+// checkProxy detects the exit ip / country and loads the search page of the engine
+// `requests` times through the proxy
+func checkProxy(proxy proxyx.TProxy, engine searchEngine.SearchEngine, text string, lr string, requests int) (proxyx.CheckReport, []time.Duration) {
+	report := proxyx.CheckReport{
+		Proxy:  proxyx.StructToStr(proxy),
+		Target: engine.Name(),
+	}
+	latencies := []time.Duration{}
+	bans := 0
+
+	if geo, err := proxyx.GetGeo(proxy); err == nil {
+		report.ExitIP = geo.Query
+		report.Country = geo.CountryCode
+	}
 
-func checkProxy(proxyAddr, testURL string, timeoutSec int, ch chan TReport) {
-	proxyURL, _ := url.Parse(proxyAddr)
+	for i := 0; i < requests; i++ {
+		startTime := time.Now()
+		resp, err := engine.Fetch(engine.GetSearchPageUrl(text, lr, 0), nil, &proxy)
+		report.Requests++
 
-	transport := &http.Transport{
-		Proxy: http.ProxyURL(proxyURL),
+		switch {
+		case err != nil:
+			report.Error = err.Error()
+		case engine.IsBlocked(resp):
+			report.Captchas++
+		case resp.Status == 403 || resp.Status == 429:
+			bans++
+			report.Error = fmt.Sprintf("status %v", resp.Status)
+		case resp.Status >= 400:
+			report.Error = fmt.Sprintf("status %v", resp.Status)
+		default:
+			report.Successes++
+			latencies = append(latencies, time.Since(startTime))
+		}
 	}
 
-	client := &http.Client{
-		Transport: transport,
-		Timeout:   time.Duration(timeoutSec) * time.Second,
+	switch {
+	case report.Captchas > 0:
+		report.Verdict = proxyx.VerdictCaptcha
+	case bans > 0:
+		report.Verdict = proxyx.VerdictBanned
+	case report.Successes == 0:
+		report.Verdict = proxyx.VerdictDead
+	default:
+		report.Verdict = proxyx.VerdictOk
+		report.Error = ""
 	}
 
-	resp, err := client.Get(testURL)
+	report.LatencyP50Ms = percentile(latencies, 50).Milliseconds()
+	report.LatencyP90Ms = percentile(latencies, 90).Milliseconds()
 
-	if err != nil {
-		ch <- TReport{proxyAddr, -1}
-		return
+	return report, latencies
+}
+
+// percentile returns the nearest-rank percentile of durations
+func percentile(durations []time.Duration, p int) time.Duration {
+	if len(durations) == 0 {
+		return 0
 	}
 
-	defer resp.Body.Close()
-	ch <- TReport{proxyAddr, resp.StatusCode}
+	sorted := slices.Clone(durations)
+	slices.Sort(sorted)
+	rank := (p*len(sorted)+99)/100 - 1
+
+	return sorted[max(rank, 0)]
 }
 
-func readProxies() []string {
-	options := map[string]map[string]string{}
-	response, _, _ := httpRequest.Get("https://api.proxytraff.com/package/get?c=K5hk", options)
+func writeReports(name string, reports []proxyx.CheckReport) error {
+	if filepath.Ext(name) != ".csv" {
+		return storage.WriteFile(name, reports)
+	}
 
-	return strings.Split(response, "\n")
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	writer.Write(proxyx.CheckReportHeader)
+
+	for _, r := range reports {
+		writer.Write([]string{
+			r.Proxy, r.Verdict, r.Target, r.ExitIP, r.Country,
+			strconv.Itoa(r.Requests), strconv.Itoa(r.Successes), strconv.Itoa(r.Captchas),
+			strconv.FormatInt(r.LatencyP50Ms, 10), strconv.FormatInt(r.LatencyP90Ms, 10), r.Error,
+		})
+	}
+
+	writer.Flush()
+
+	if err := writer.Error(); err != nil {
+		return err
+	}
+
+	return storage.WriteFile(name, buf.Bytes())
 }
 
 func main() {
-	proxies := readProxies()[:10]
-	targetURL := "https://yandex.ru"
-	timeout := 1
-	reports := make(chan TReport)
-	sem := make(chan struct{}, 1) // семафор с десятью слотами
+	sources := flag.String("sources", config.ProxySources, "comma separated proxy sources: provider, file, list, command")
+	engineName := flag.String("engine", "yandex", "search engine to check the proxies against")
+	text := flag.String("text", "купить телефон", "search query of the check")
+	lr := flag.String("lr", "213", "region of the search query")
+	timeout := flag.Duration("timeout", time.Second*10, "timeout of the geo lookup and of every search request")
+	requests := flag.Int("requests", 3, "search requests per proxy")
+	concurrency := flag.Int("concurrency", 20, "proxies checked in parallel")
+	limit := flag.Int("limit", 0, "check first N proxies (0 - all)")
+	out := flag.String("out", "proxy/checked.json", "storage file of the reports (.json or .csv), can be used as config.ProxyFile")
+	flag.Parse()
+
+	godotenv.Load()
+
+	// a stalled proxy must not hold a concurrency slot
+	httpRequest.Timeout = *timeout
+
+	engine, err := searchEngine.Get(*engineName)
+
+	if err != nil {
+		log.Fatal(err)
+	}
 
+	proxySources, err := proxyx.GetSources(*sources)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	proxies, err := proxyx.LoadSources(proxySources)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *limit > 0 && *limit < len(proxies) {
+		proxies = proxies[:*limit]
+	}
+
+	log.Printf("[INFO] Check %v proxies against %v (concurrency %v)", len(proxies), engine.Name(), *concurrency)
+
+	reports := make([]proxyx.CheckReport, len(proxies))
+	latencies := []time.Duration{}
+	sem := make(chan struct{}, max(*concurrency, 1))
+
+	var mu sync.Mutex
 	var wg sync.WaitGroup
 
-	for _, proxy := range proxies {
+	for i, proxy := range proxies {
 		wg.Add(1)
-		go func(proxy string) {
+		go func(i int, proxy proxyx.TProxy) {
 			defer wg.Done()
-			sem <- struct{}{} // занять слот (если занято 10 – блокируется)
-			checkProxy(proxy, targetURL, timeout, reports)
-			<-sem // освободить слот
-		}(proxy)
+			sem <- struct{}{} // block slot
+
+			report, proxyLatencies := checkProxy(proxy, engine, *text, *lr, *requests)
+			log.Printf("[INFO] %v: %v %v %v p50=%vms", report.Proxy, report.Verdict, report.ExitIP, report.Country, report.LatencyP50Ms)
+
+			mu.Lock()
+			reports[i] = report
+			latencies = append(latencies, proxyLatencies...)
+			mu.Unlock()
+
+			<-sem // free slot
+		}(i, proxy)
 	}
 
-	// горутина для закрытия канала с результатами
-	go func() {
-		wg.Wait()
-		close(reports)
-	}()
+	wg.Wait()
 
-	for report := range reports {
-		fmt.Printf("Прокси %s: %d\n", report.proxy, report.status)
+	verdicts := map[string]int{}
+
+	for _, report := range reports {
+		verdicts[report.Verdict]++
 	}
+
+	log.Printf("[INFO] Verdicts: %v %v, %v %v, %v %v, %v %v",
+		proxyx.VerdictOk, verdicts[proxyx.VerdictOk],
+		proxyx.VerdictCaptcha, verdicts[proxyx.VerdictCaptcha],
+		proxyx.VerdictBanned, verdicts[proxyx.VerdictBanned],
+		proxyx.VerdictDead, verdicts[proxyx.VerdictDead],
+	)
+	log.Printf("[INFO] Latency: p50 %v, p90 %v, p99 %v",
+		percentile(latencies, 50).Round(time.Millisecond),
+		percentile(latencies, 90).Round(time.Millisecond),
+		percentile(latencies, 99).Round(time.Millisecond),
+	)
+
+	if err := writeReports(*out, reports); err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("[INFO] Reports saved to %v", *out)
 }
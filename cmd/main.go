@@ -1,14 +1,26 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"github.com/joho/godotenv"
 	"log"
 	"math"
+	_ "parser/services/anticaptcha"
+	browserCtl "parser/services/browserctl"
+	_ "parser/services/capsola"
+	"parser/services/captcha"
 	"parser/services/config"
+	"parser/services/journal"
 	"parser/services/proxyx"
-	"parser/services/searchYandex"
+	_ "parser/services/rucaptcha"
+	_ "parser/services/searchBing"
+	_ "parser/services/searchDuckDuckGo"
+	"parser/services/searchEngine"
+	_ "parser/services/searchGoogle"
+	_ "parser/services/searchYandex"
 	"parser/services/storage"
+	"parser/services/warmup"
 	"slices"
 	"strings"
 	"sync"
@@ -17,12 +29,49 @@ import (
 
 func init() {
 	godotenv.Load()
-	proxyx.Init()
+
+	if config.UseProxy {
+		if err := proxyx.Init(); err != nil {
+			log.Fatal(err)
+		}
+	}
 }
 
 func main() {
+	resume := flag.String("resume", "", "id of the interrupted run to resume")
+	flag.Parse()
+
+	engines, err := searchEngine.GetList(config.Engines)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	runId := *resume
+
+	if runId == "" {
+		runId = journal.NewRunId()
+	}
+
+	runJournal, err := journal.Open(runId, *resume != "")
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *resume != "" {
+		log.Printf("[INFO] Resume run %v (%v keyword(s) finished)", runId, runJournal.Done())
+	} else {
+		log.Printf("[INFO] Start run %v", runId)
+	}
+
 	//fetch sources data
-	dataStr := storage.ReadFile("test/10000.txt")
+	dataStr, err := storage.ReadFile("test/10000.txt")
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	kw := strings.Split(dataStr, "\n")[0:config.KwNumber]
 
 	//chunk source data
@@ -30,52 +79,78 @@ func main() {
 	chunks := slices.Chunk(kw, int(chunkSize))
 
 	//[start] process input data
-	log.Printf("[INFO] Parse %v keyword(s)", config.KwNumber)
+	log.Printf("[INFO] Parse %v keyword(s) with %v", config.KwNumber, config.Engines)
 
 	startTime := time.Now()
-	resultsCh := make(chan searchYandex.TResult)
+	resultsCh := make(chan searchEngine.TResult)
 	sem := make(chan struct{}, config.Threads) // семафор с десятью слотами
 
 	var wg sync.WaitGroup
 
-	for chunk := range chunks {
-		time.Sleep(time.Second * 3)
-		wg.Add(1)
-		go func(chunk []string) {
-			defer wg.Done()
-			searchYandex.ParseKeywordsListRoutine(chunk, "46", resultsCh)
-			sem <- struct{}{} // block slot
+	for _, engine := range engines {
+		for chunk := range chunks {
+			time.Sleep(time.Second * 3)
+			wg.Add(1)
+			go func(engine searchEngine.SearchEngine, chunk []string) {
+				defer wg.Done()
+				searchEngine.ParseKeywordsListRoutine(engine, chunk, "46", runJournal, resultsCh)
+				sem <- struct{}{} // block slot
 
-			<-sem // free slot
-		}(chunk)
+				<-sem // free slot
+			}(engine, chunk)
+		}
 	}
 
 	// close channel
 	go func() {
 		wg.Wait()
+		searchEngine.CloseSessionPools()
+		browserCtl.ClosePool()
 		close(resultsCh)
 	}()
 
-	var items = []searchYandex.SERPItem{}
-	var stats = searchYandex.Stats{}
+	var failed = []searchEngine.FailureRecord{}
 
+	// results and their stats are streamed to the journal by the runner. Captcha and warm-up
+	// counters are process wide: the ones gathered since the previous chunk are saved with it,
+	// so a killed run keeps them for the resume.
 	for result := range resultsCh {
-		items = append(items, result.Items...)
+		failed = append(failed, result.Failed...)
+		chunkStats := searchEngine.Stats{Captcha: captcha.TakeStats(), Warmup: warmup.TakeStats()}
+
+		if err := runJournal.AddStats(chunkStats); err != nil {
+			log.Printf("[WARN] Can't save stats: %v", err)
+		}
+	}
 
-		stats.TotalPages += result.Stats.TotalPages
-		stats.TotalCaptchaSolved += result.Stats.TotalCaptchaSolved
-		stats.AccessSuspended += result.Stats.AccessSuspended
+	// counters of the session pools closed after the last chunk
+	if err := runJournal.AddStats(searchEngine.Stats{Captcha: captcha.TakeStats(), Warmup: warmup.TakeStats()}); err != nil {
+		log.Printf("[WARN] Can't save stats: %v", err)
 	}
 
-	elapsed := time.Since(startTime)
-	hours := int(elapsed.Hours())
-	minutes := int(elapsed.Minutes()) % 60
-	seconds := int(elapsed.Seconds()) % 60
-	stats.TimeSpend = fmt.Sprintf("%02d:%02d:%02d", hours, minutes, seconds)
+	stats := runJournal.Stats()
+	stats.TimeSpend = searchEngine.FormatDuration(time.Since(startTime))
+	stats.FailedKeywords = len(failed)
+	items, blocks, err := runJournal.Results()
+
+	if err != nil {
+		log.Fatal(err)
+	}
 	//[end]
 
 	//output results
 	dir := fmt.Sprintf("parsed/load-kw-test-%v", config.KwNumber)
-	storage.WriteFile(dir+"/result.json", items)
-	storage.WriteFile(dir+"/stats.json", stats)
+	outputs := map[string]any{
+		"result.json":   items,
+		"blocks.json":   blocks,
+		"stats.json":    stats,
+		"failures.json": failed,
+		"proxies.json":  proxyx.PoolState(),
+	}
+
+	for name, content := range outputs {
+		if err := storage.WriteFile(dir+"/"+name, content); err != nil {
+			log.Printf("[ERROR] %v", err)
+		}
+	}
 }
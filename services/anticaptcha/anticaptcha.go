@@ -0,0 +1,83 @@
+/**
+ * package anticaptcha
+ *
+ * Anti-Captcha solver. The key is taken from ANTICAPTCHA_API_KEY.
+ * ImageToCoordinatesTask has no instruction image, workers get the task comment only.
+ */
+
+package anticaptcha
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"parser/services/captcha"
+	"parser/services/config"
+	"parser/services/geometry"
+)
+
+type Solver struct{}
+
+func init() {
+	captcha.Register(Solver{})
+}
+
+func (Solver) api() captcha.TaskApi {
+	return captcha.TaskApi{
+		Url: config.AntiCaptchaUrl,
+		Key: os.Getenv("ANTICAPTCHA_API_KEY"),
+	}
+}
+
+func (Solver) Name() string {
+	return "anticaptcha"
+}
+
+func (s Solver) Submit(task captcha.Task) (string, error) {
+	switch task.Type {
+	case captcha.TaskSilhouette, captcha.TaskSlider:
+		return s.api().CreateTask(map[string]any{
+			"type":    "ImageToCoordinatesTask",
+			"body":    base64.StdEncoding.EncodeToString(task.Image),
+			"comment": task.Comment,
+			"mode":    "points",
+		})
+	case captcha.TaskText:
+		return s.api().CreateTask(map[string]any{
+			"type":    "ImageToTextTask",
+			"body":    base64.StdEncoding.EncodeToString(task.Image),
+			"comment": task.Comment,
+		})
+	}
+
+	return "", fmt.Errorf("anticaptcha: unsupported task type `%v`", task.Type)
+}
+
+func (s Solver) Poll(taskId string) (captcha.Solution, error) {
+	var solution struct {
+		Coordinates [][]float64 `json:"coordinates"`
+		Text        string      `json:"text"`
+	}
+
+	if err := s.api().GetTaskResult(taskId, &solution); err != nil {
+		return captcha.Solution{}, err
+	}
+
+	result := captcha.Solution{
+		Text: solution.Text,
+	}
+
+	for _, point := range solution.Coordinates {
+		if len(point) < 2 {
+			return captcha.Solution{}, fmt.Errorf("anticaptcha: bad point %v", point)
+		}
+
+		result.Points = append(result.Points, geometry.Point{X: point[0], Y: point[1]})
+	}
+
+	return result, nil
+}
+
+func (s Solver) ReportBad(taskId string) error {
+	return s.api().Report("reportIncorrectImageCaptcha", taskId)
+}
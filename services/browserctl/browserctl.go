@@ -8,43 +8,107 @@ package browserCtl
 
 import (
 	"context"
+	"fmt"
+	"github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/cdproto/emulation"
 	"github.com/chromedp/cdproto/fetch"
 	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/storage"
 	"github.com/chromedp/chromedp"
 	"log"
+	"net"
 	"parser/services/config"
 	"parser/services/proxyx"
 	"parser/services/useragent"
 )
 
-// Search Engine Results Page Item
-type SERPItem struct {
-	Pos    int    `json:"pos"`
-	URL    string `json:"url"`
-	Domain string `json:"domain"`
-	Title  string `json:"title"`
-	Text   string `json:"text"`
-}
-
 type GetContextOptions struct {
-	Proxy *proxyx.TProxy
+	Proxy       *proxyx.TProxy
+	Fingerprint *useragent.FingerprintProfile // random when nil
 }
 
+// GetContext returns a browser context with the proxy and fingerprint of the session: an incognito
+// context of the browser pool (config.BrowserPoolSize > 0) or a new chrome process
 func GetContext(parent context.Context, options GetContextOptions) (context.Context, context.CancelFunc) {
-	opts := append(chromedp.DefaultExecAllocatorOptions[:],
+	profile := options.Fingerprint
+
+	if profile == nil {
+		random := useragent.NewFingerprint()
+		profile = &random
+	}
+
+	var ctx context.Context
+	var cancel context.CancelFunc
+
+	if config.BrowserPoolSize > 0 {
+		var err error
+		ctx, cancel, err = defaultPool().NewContext(parent, options.Proxy)
+
+		if err != nil {
+			log.Printf("[ERROR] Browser pool: %v, start a separate chrome", err)
+		}
+	}
+
+	if ctx == nil {
+		ctx, cancel = newProcessContext(parent, options.Proxy, *profile)
+	}
+
+	// socks5 credentials are passed by the forwarder
+	if options.Proxy != nil && options.Proxy.User != "" && !options.Proxy.IsSocks() {
+		if err := handleProxyAuth(ctx, *options.Proxy); err != nil {
+			log.Printf("[ERROR] Can't enable proxy auth: %v", err)
+		}
+	}
+
+	if err := chromedp.Run(ctx, emulateFingerprint(*profile)); err != nil {
+		log.Printf("[ERROR] Can't apply fingerprint profile: %v", err)
+	}
+
+	var cancelTimeout context.CancelFunc
+
+	if config.TimeOutSec > 0 {
+		ctx, cancelTimeout = context.WithTimeout(ctx, config.TimeOutSec)
+	}
+
+	cancelAll := func() {
+		if cancelTimeout != nil {
+			cancelTimeout()
+		}
+
+		cancel()
+	}
+
+	return ctx, cancelAll
+}
+
+// browserFlags are the command line flags of every chrome process
+func browserFlags() []chromedp.ExecAllocatorOption {
+	return append(chromedp.DefaultExecAllocatorOptions[:],
 		chromedp.Flag("headless", config.Headless),
 		chromedp.Flag("disable-gpu", true),
-		chromedp.UserAgent(useragent.RandomUserAgent()),
-		chromedp.Flag("accept-lang", "ru-RU,ru;q=0.9,en;q=0.8"),
-		chromedp.Flag("window-size", "960,640"),
 		chromedp.Flag("start-maximized", false),
 		chromedp.Flag("enable-automation", false),
 		chromedp.Flag("disable-blink-features", "AutomationControlled"),
 	)
+}
+
+// newProcessContext starts a chrome process for the session
+func newProcessContext(parent context.Context, proxy *proxyx.TProxy, profile useragent.FingerprintProfile) (context.Context, context.CancelFunc) {
+	opts := append(browserFlags(),
+		chromedp.UserAgent(profile.UserAgent),
+		chromedp.Flag("accept-lang", profile.AcceptLanguage()),
+		chromedp.Flag("lang", profile.Locale()),
+		chromedp.WindowSize(profile.ScreenWidth, profile.ScreenHeight),
+	)
+
+	var forwarder *proxyx.Forwarder
+
+	if proxy != nil {
+		var proxyServer string
+		proxyServer, forwarder = getProxyServer(*proxy)
 
-	if config.UseProxy && options.Proxy != nil {
 		opts = append(opts,
-			chromedp.ProxyServer(proxyx.StructToStr(*options.Proxy)),
+			chromedp.ProxyServer(proxyServer),
 			chromedp.Flag("proxy-bypass-list", "<-loopback>"),
 		)
 	}
@@ -53,58 +117,112 @@ func GetContext(parent context.Context, options GetContextOptions) (context.Cont
 
 	ctx, cancelCtx := chromedp.NewContext(allocCtx)
 
-	if config.UseProxy && options.Proxy != nil && options.Proxy.User != "" {
-		chromedp.ListenTarget(ctx, func(ev interface{}) {
-			switch ev := ev.(type) {
-			case *fetch.EventAuthRequired:
-				if ev.AuthChallenge.Source == fetch.AuthChallengeSourceProxy {
-					go func() {
-						err := chromedp.Run(ctx, fetch.ContinueWithAuth(ev.RequestID, &fetch.AuthChallengeResponse{
-							Response: fetch.AuthChallengeResponseResponseProvideCredentials,
-							Username: options.Proxy.User,
-							Password: options.Proxy.Pass,
-						}))
-						if err != nil {
-							log.Printf("auth error: %v", err)
-						}
-					}()
-				}
-			case *fetch.EventRequestPaused:
+	cancel := func() {
+		cancelCtx()
+		cancelAlloc()
+
+		if forwarder != nil {
+			forwarder.Close()
+		}
+	}
+
+	return ctx, cancel
+}
+
+// handleProxyAuth answers proxy auth challenges of the context with the proxy credentials
+func handleProxyAuth(ctx context.Context, proxy proxyx.TProxy) error {
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		switch ev := ev.(type) {
+		case *fetch.EventAuthRequired:
+			if ev.AuthChallenge.Source == fetch.AuthChallengeSourceProxy {
 				go func() {
-					_ = chromedp.Run(ctx, fetch.ContinueRequest(ev.RequestID))
+					err := chromedp.Run(ctx, fetch.ContinueWithAuth(ev.RequestID, &fetch.AuthChallengeResponse{
+						Response: fetch.AuthChallengeResponseResponseProvideCredentials,
+						Username: proxy.User,
+						Password: proxy.Pass,
+					}))
+					if err != nil {
+						log.Printf("auth error: %v", err)
+					}
 				}()
 			}
-		})
+		case *fetch.EventRequestPaused:
+			go func() {
+				_ = chromedp.Run(ctx, fetch.ContinueRequest(ev.RequestID))
+			}()
+		}
+	})
 
-		err := chromedp.Run(ctx,
-			fetch.Enable().WithHandleAuthRequests(true),
-		)
+	return chromedp.Run(ctx,
+		fetch.Enable().WithHandleAuthRequests(true),
+	)
+}
 
-		if err != nil {
-			log.Fatal(err)
-		}
-	}
+// emulateFingerprint overrides what the command line flags can't set or pooled browsers share:
+// screen and window size, client hints of the profile instead of the installed chrome version, navigator.platform,
+// locale and timezone
+func emulateFingerprint(profile useragent.FingerprintProfile) chromedp.Tasks {
+	brands := []*emulation.UserAgentBrandVersion{}
+	fullVersions := []*emulation.UserAgentBrandVersion{}
 
-	cancel := func() {
-		cancelCtx()
-		cancelAlloc()
+	for _, brand := range profile.Brands() {
+		brands = append(brands, &emulation.UserAgentBrandVersion{Brand: brand.Brand, Version: brand.Version})
+		fullVersions = append(fullVersions, &emulation.UserAgentBrandVersion{Brand: brand.Brand, Version: brand.Version + ".0.0.0"})
 	}
 
-	var cancelTimeout context.CancelFunc
+	return chromedp.Tasks{
+		emulation.SetDeviceMetricsOverride(int64(profile.ScreenWidth), int64(profile.ScreenHeight), 1, profile.Mobile).
+			WithScreenWidth(int64(profile.ScreenWidth)).
+			WithScreenHeight(int64(profile.ScreenHeight)),
+		emulation.SetUserAgentOverride(profile.UserAgent).
+			WithAcceptLanguage(profile.AcceptLanguage()).
+			WithPlatform(profile.NavigatorPlatform).
+			WithUserAgentMetadata(&emulation.UserAgentMetadata{
+				Brands:          brands,
+				FullVersionList: fullVersions,
+				Platform:        profile.Platform,
+				PlatformVersion: profile.PlatformVersion,
+				Architecture:    profile.Architecture,
+				Mobile:          profile.Mobile,
+				Bitness:         "64",
+			}),
+		emulation.SetLocaleOverride().WithLocale(profile.Locale()),
+		emulation.SetTimezoneOverride(profile.Timezone),
+		// outer size of the window: pooled processes are started without --window-size
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			windowID, _, err := browser.GetWindowForTarget().Do(ctx)
 
-	if config.TimeOutSec > 0 {
-		ctx, cancelTimeout = context.WithTimeout(ctx, config.TimeOutSec)
+			if err != nil {
+				return err
+			}
+
+			return browser.SetWindowBounds(windowID, &browser.Bounds{
+				Width:  int64(profile.ScreenWidth),
+				Height: int64(profile.ScreenHeight),
+			}).Do(ctx)
+		}),
 	}
+}
 
-	cancelAll := func() {
-		if cancelTimeout != nil {
-			cancelTimeout()
+// getProxyServer returns the --proxy-server value. Chrome resolves hosts through socks5
+// proxies itself (socks5h is passed as socks5) and can't authenticate to them, so
+// authenticated socks5 proxies are served by a local forwarder.
+func getProxyServer(proxy proxyx.TProxy) (string, *proxyx.Forwarder) {
+	if !proxy.IsSocks() {
+		return proxyx.StructToStr(proxy), nil
+	}
+
+	if proxy.User != "" {
+		forwarder, err := proxyx.StartForwarder(proxy)
+
+		if err == nil {
+			return forwarder.Addr(), forwarder
 		}
 
-		cancel()
+		log.Printf("[ERROR] Can't start socks5 forwarder: %v", err)
 	}
 
-	return ctx, cancelAll
+	return fmt.Sprintf("%v://%v", proxyx.ProtocolSocks5, net.JoinHostPort(proxy.Host, proxy.Port)), nil
 }
 
 func SetCookiesFromNetworkCookies(ctx context.Context, cookies []*network.Cookie) error {
@@ -126,3 +244,19 @@ func SetCookiesFromNetworkCookies(ctx context.Context, cookies []*network.Cookie
 
 	return network.SetCookies(cookieParams).Do(ctx)
 }
+
+// GetCookies returns all browser cookies of the context
+func GetCookies(ctx context.Context) []*network.Cookie {
+	var cookies []*network.Cookie
+
+	chromedp.Run(
+		ctx,
+		chromedp.ActionFunc(func(ctx context.Context) error {
+			var err error
+			cookies, err = storage.GetCookies().Do(ctx)
+			return err
+		}),
+	)
+
+	return cookies
+}
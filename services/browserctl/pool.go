@@ -0,0 +1,388 @@
+package browserCtl
+
+import (
+	"context"
+	"fmt"
+	"github.com/chromedp/cdproto/target"
+	"github.com/chromedp/chromedp"
+	"log"
+	"os"
+	"parser/services/config"
+	"parser/services/proxyx"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// BrowserPool keeps up to config.BrowserPoolSize chrome processes alive and hands out isolated
+// incognito contexts (own cookies, cache and proxy) of them, up to config.BrowserMaxTabs per
+// process. A crashed process is dropped and a new one is started on demand, a process that
+// exceeds config.BrowserMaxMemoryMb or served config.BrowserRecycleContexts contexts is drained:
+// it gets no new contexts and is closed when the last one is released.
+type BrowserPool struct {
+	size    int
+	maxTabs int
+	recycle int // contexts served by a process before it is drained (0 - never)
+
+	tabs chan struct{} // limits open contexts of all processes
+
+	mu        sync.Mutex
+	browsers  []*pooledBrowser
+	launching chan struct{} // closed when the process being started is registered, nil - no launch
+	closed    bool
+
+	launch func() (*pooledBrowser, error) // starts a chrome process
+}
+
+type pooledBrowser struct {
+	ctx    context.Context // root context, done when chrome exits
+	cancel context.CancelFunc
+	pid    int
+
+	tabs     int
+	served   int
+	draining bool
+	closing  bool
+}
+
+var (
+	poolMu sync.Mutex
+	pool   *BrowserPool
+)
+
+// defaultPool returns the pool shared by the sessions of the process
+func defaultPool() *BrowserPool {
+	poolMu.Lock()
+	defer poolMu.Unlock()
+
+	if pool == nil {
+		pool = NewBrowserPool(config.BrowserPoolSize, config.BrowserMaxTabs)
+	}
+
+	return pool
+}
+
+// ClosePool closes chrome processes of the shared pool
+func ClosePool() {
+	poolMu.Lock()
+	defer poolMu.Unlock()
+
+	if pool != nil {
+		pool.Close()
+		pool = nil
+	}
+}
+
+func NewBrowserPool(size int, maxTabs int) *BrowserPool {
+	size = max(size, 1)
+	maxTabs = max(maxTabs, 1)
+
+	return &BrowserPool{
+		size:    size,
+		maxTabs: maxTabs,
+		recycle: config.BrowserRecycleContexts,
+		tabs:    make(chan struct{}, size*maxTabs),
+		launch:  launchChrome,
+	}
+}
+
+// NewContext returns an incognito context with the proxy, waiting for a free tab while all
+// of them are busy. The cancel func closes the context and frees the tab.
+func (p *BrowserPool) NewContext(parent context.Context, proxy *proxyx.TProxy) (context.Context, context.CancelFunc, error) {
+	select {
+	case p.tabs <- struct{}{}: // block tab
+	case <-parent.Done():
+		return nil, nil, parent.Err()
+	}
+
+	browser, err := p.acquire()
+
+	if err != nil {
+		<-p.tabs
+		return nil, nil, err
+	}
+
+	var forwarder *proxyx.Forwarder
+	var contextOptions []chromedp.CreateBrowserContextOption
+
+	if proxy != nil {
+		var proxyServer string
+		proxyServer, forwarder = getProxyServer(*proxy)
+
+		contextOptions = append(contextOptions, func(params *target.CreateBrowserContextParams) *target.CreateBrowserContextParams {
+			return params.WithProxyServer(proxyServer).WithProxyBypassList("<-loopback>")
+		})
+	}
+
+	ctx, cancelCtx := chromedp.NewContext(browser.ctx, chromedp.WithNewBrowserContext(contextOptions...))
+	stopParent := context.AfterFunc(parent, cancelCtx)
+
+	var once sync.Once
+
+	cancel := func() {
+		once.Do(func() {
+			stopParent()
+			cancelCtx()
+
+			if forwarder != nil {
+				forwarder.Close()
+			}
+
+			p.release(browser)
+			<-p.tabs // free tab
+		})
+	}
+
+	return ctx, cancel, nil
+}
+
+// acquire returns the least busy running process, starting a new one when all are busy.
+// While a process is being started the others wait for it, it may have free tabs for them.
+func (p *BrowserPool) acquire() (*pooledBrowser, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for {
+		if p.closed {
+			return nil, fmt.Errorf("pool is closed")
+		}
+
+		var best *pooledBrowser
+		running := 0
+
+		for _, b := range p.browsers {
+			if b.draining || b.ctx.Err() != nil {
+				continue
+			}
+
+			running++
+
+			if b.tabs < p.maxTabs && (best == nil || b.tabs < best.tabs) {
+				best = b
+			}
+		}
+
+		if best == nil && p.launching != nil {
+			launching := p.launching
+			p.mu.Unlock()
+			<-launching
+			p.mu.Lock()
+
+			continue
+		}
+
+		if best == nil {
+			if running >= p.size {
+				return nil, fmt.Errorf("all %v browsers are busy", running)
+			}
+
+			var err error
+			best, err = p.start()
+
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		best.tabs++
+		best.served++
+
+		return best, nil
+	}
+}
+
+// start runs a chrome process and watches it for a crash. p.mu must be held, it is released
+// while chrome launches and held again on return.
+func (p *BrowserPool) start() (*pooledBrowser, error) {
+	launching := make(chan struct{})
+	p.launching = launching
+	p.mu.Unlock()
+
+	b, err := p.launch()
+
+	p.mu.Lock()
+	p.launching = nil
+	close(launching)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// closed during the launch
+	if p.closed {
+		b.closing = true
+		go b.cancel()
+
+		return nil, fmt.Errorf("pool is closed")
+	}
+
+	p.browsers = append(p.browsers, b)
+	log.Printf("[INFO] Browser pool: chrome started (pid=%v, running %v)", b.pid, len(p.browsers))
+
+	go func() {
+		<-b.ctx.Done()
+
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		if !b.closing {
+			log.Printf("[WARN] Browser pool: chrome crashed (pid=%v, %v open contexts), restart on demand", b.pid, b.tabs)
+			b.cancel()
+		}
+
+		p.remove(b)
+	}()
+
+	return b, nil
+}
+
+func launchChrome() (*pooledBrowser, error) {
+	allocCtx, cancelAlloc := chromedp.NewExecAllocator(context.Background(), browserFlags()...)
+	ctx, cancelCtx := chromedp.NewContext(allocCtx)
+
+	b := &pooledBrowser{
+		ctx: ctx,
+		cancel: func() {
+			cancelCtx()
+			cancelAlloc()
+		},
+	}
+
+	if err := chromedp.Run(ctx); err != nil {
+		b.cancel()
+		return nil, err
+	}
+
+	if process := chromedp.FromContext(ctx).Browser.Process(); process != nil {
+		b.pid = process.Pid
+	}
+
+	return b, nil
+}
+
+// release frees the tab of the process and drains the process when it hit a limit
+func (p *BrowserPool) release(b *pooledBrowser) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	b.tabs--
+
+	if !b.draining {
+		reason := ""
+
+		if p.recycle > 0 && b.served >= p.recycle {
+			reason = fmt.Sprintf("served %v contexts", b.served)
+		} else if memory := processTreeMemoryMb(b.pid); config.BrowserMaxMemoryMb > 0 && memory > config.BrowserMaxMemoryMb {
+			reason = fmt.Sprintf("uses %vMb", memory)
+		}
+
+		if reason != "" {
+			log.Printf("[INFO] Browser pool: restart chrome (pid=%v): %v", b.pid, reason)
+			b.draining = true
+		}
+	}
+
+	if b.draining && b.tabs <= 0 {
+		p.stop(b)
+	}
+}
+
+// stop closes the process, p.mu must be held
+func (p *BrowserPool) stop(b *pooledBrowser) {
+	if b.closing {
+		return
+	}
+
+	b.closing = true
+	go b.cancel() // waits for chrome to exit
+	p.remove(b)
+}
+
+func (p *BrowserPool) remove(b *pooledBrowser) {
+	if i := slices.Index(p.browsers, b); i >= 0 {
+		p.browsers = slices.Delete(p.browsers, i, i+1)
+	}
+}
+
+// Close closes all processes, open contexts are cancelled
+func (p *BrowserPool) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.closed = true
+
+	for _, b := range slices.Clone(p.browsers) {
+		p.stop(b)
+	}
+}
+
+// processTreeMemoryMb returns resident memory of the process and its children (renderers,
+// gpu and utility processes) from /proc, 0 when it is not available
+func processTreeMemoryMb(pid int) int {
+	if pid == 0 {
+		return 0
+	}
+
+	entries, err := os.ReadDir("/proc")
+
+	if err != nil {
+		return 0
+	}
+
+	children := map[int][]int{}
+
+	for _, entry := range entries {
+		child, err := strconv.Atoi(entry.Name())
+
+		if err != nil {
+			continue
+		}
+
+		stat, err := os.ReadFile("/proc/" + entry.Name() + "/stat")
+
+		if err != nil {
+			continue
+		}
+
+		// pid (comm) state ppid ..., comm may contain spaces
+		_, rest, _ := strings.Cut(string(stat), ") ")
+		fields := strings.Fields(rest)
+
+		if len(fields) < 2 {
+			continue
+		}
+
+		if parent, err := strconv.Atoi(fields[1]); err == nil {
+			children[parent] = append(children[parent], child)
+		}
+	}
+
+	pageSize := int64(os.Getpagesize())
+	var rss int64
+
+	queue := []int{pid}
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = append(queue[1:], children[current]...)
+
+		statm, err := os.ReadFile("/proc/" + strconv.Itoa(current) + "/statm")
+
+		if err != nil {
+			continue
+		}
+
+		fields := strings.Fields(string(statm))
+
+		if len(fields) < 2 {
+			continue
+		}
+
+		if pages, err := strconv.ParseInt(fields[1], 10, 64); err == nil {
+			rss += pages * pageSize
+		}
+	}
+
+	return int(rss / 1024 / 1024)
+}
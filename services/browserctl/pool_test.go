@@ -0,0 +1,250 @@
+package browserCtl
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeLauncher starts "browsers" that crash when their context is cancelled
+type fakeLauncher struct {
+	mu       sync.Mutex
+	launched []*pooledBrowser
+	stopped  int
+}
+
+func (l *fakeLauncher) launch() (*pooledBrowser, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	b := &pooledBrowser{ctx: ctx}
+	b.cancel = func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		if ctx.Err() == nil {
+			l.stopped++
+		}
+
+		cancel()
+	}
+
+	l.launched = append(l.launched, b)
+
+	return b, nil
+}
+
+func newTestPool(size int, maxTabs int) (*BrowserPool, *fakeLauncher) {
+	launcher := &fakeLauncher{}
+	p := NewBrowserPool(size, maxTabs)
+	p.launch = launcher.launch
+
+	return p, launcher
+}
+
+func (p *BrowserPool) running() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return len(p.browsers)
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+
+	for i := 0; i < 100; i++ {
+		if condition() {
+			return
+		}
+
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	t.Fatalf("condition not met")
+}
+
+func TestBrowserPoolSpreadsTabs(t *testing.T) {
+	p, launcher := newTestPool(2, 2)
+
+	for i := 0; i < 4; i++ {
+		if _, err := p.acquire(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(launcher.launched) != 2 {
+		t.Errorf("expected 2 browsers, got %v", len(launcher.launched))
+	}
+
+	for _, b := range launcher.launched {
+		if b.tabs != 2 {
+			t.Errorf("expected 2 tabs per browser, got %v", b.tabs)
+		}
+	}
+
+	if _, err := p.acquire(); err == nil {
+		t.Errorf("expected an error when all tabs are busy")
+	}
+}
+
+func TestBrowserPoolRestartsCrashedBrowser(t *testing.T) {
+	p, launcher := newTestPool(1, 2)
+	crashed, _ := p.acquire()
+
+	// chrome exits: the browser is dropped, its open contexts are released later
+	launcher.launched[0].cancel()
+	waitFor(t, func() bool { return p.running() == 0 })
+
+	restarted, err := p.acquire()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if restarted == crashed || len(launcher.launched) != 2 {
+		t.Errorf("expected a new browser after the crash")
+	}
+
+	p.release(crashed)
+
+	if p.running() != 1 {
+		t.Errorf("release of a crashed browser context changed the pool")
+	}
+}
+
+func TestBrowserPoolDrainsRecycledBrowser(t *testing.T) {
+	p, launcher := newTestPool(1, 2)
+	p.recycle = 5
+	open, _ := p.acquire()
+
+	for i := 1; i < p.recycle; i++ {
+		b, _ := p.acquire()
+		p.release(b)
+	}
+
+	// the limit is reached: no new contexts, the browser is closed with the last one
+	if !open.draining || launcher.stopped != 0 {
+		t.Fatalf("expected a draining browser with an open context (draining=%v stopped=%v)", open.draining, launcher.stopped)
+	}
+
+	next, err := p.acquire()
+
+	if err != nil || next == open {
+		t.Fatalf("expected a new browser for the next context (%v)", err)
+	}
+
+	p.release(open)
+	waitFor(t, func() bool {
+		launcher.mu.Lock()
+		defer launcher.mu.Unlock()
+
+		return launcher.stopped == 1
+	})
+
+	if p.running() != 1 {
+		t.Errorf("expected 1 running browser, got %v", p.running())
+	}
+}
+
+func TestBrowserPoolClose(t *testing.T) {
+	p, launcher := newTestPool(2, 1)
+	p.acquire()
+	p.acquire()
+	p.Close()
+
+	waitFor(t, func() bool {
+		launcher.mu.Lock()
+		defer launcher.mu.Unlock()
+
+		return launcher.stopped == 2
+	})
+
+	if _, err := p.acquire(); err == nil {
+		t.Errorf("expected an error of the closed pool")
+	}
+}
+
+func TestBrowserPoolLaunchesWithoutLock(t *testing.T) {
+	p, launcher := newTestPool(1, 2)
+	gate := make(chan struct{})
+	p.launch = func() (*pooledBrowser, error) {
+		<-gate
+		return launcher.launch()
+	}
+
+	acquired := make(chan *pooledBrowser, 2)
+
+	for i := 0; i < 2; i++ {
+		go func() {
+			b, err := p.acquire()
+
+			if err != nil {
+				t.Error(err)
+			}
+
+			acquired <- b
+		}()
+	}
+
+	// the pool is not locked while chrome starts
+	waitFor(t, func() bool {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		return p.launching != nil
+	})
+
+	if p.running() != 0 {
+		t.Errorf("expected no registered browser during the launch")
+	}
+
+	close(gate)
+	first, second := <-acquired, <-acquired
+
+	if first == nil || first != second || len(launcher.launched) != 1 {
+		t.Errorf("expected both contexts in the single launched browser, got %v launch(es)", len(launcher.launched))
+	}
+}
+
+func TestBrowserPoolClosedDuringLaunch(t *testing.T) {
+	p, launcher := newTestPool(1, 1)
+	gate := make(chan struct{})
+	p.launch = func() (*pooledBrowser, error) {
+		<-gate
+		return launcher.launch()
+	}
+
+	errs := make(chan error)
+
+	go func() {
+		_, err := p.acquire()
+		errs <- err
+	}()
+
+	waitFor(t, func() bool {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		return p.launching != nil
+	})
+
+	p.Close()
+	close(gate)
+
+	if err := <-errs; err == nil {
+		t.Errorf("expected an error of the closed pool")
+	}
+
+	waitFor(t, func() bool {
+		launcher.mu.Lock()
+		defer launcher.mu.Unlock()
+
+		return launcher.stopped == 1
+	})
+
+	if p.running() != 0 {
+		t.Errorf("expected the late browser closed, got %v running", p.running())
+	}
+}
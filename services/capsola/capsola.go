@@ -3,61 +3,164 @@ package capsola
 import (
 	"encoding/base64"
 	"encoding/json"
-	"io"
-	"log"
-	"net/http"
+	"fmt"
 	"os"
+	"parser/services/captcha"
 	"parser/services/geometry"
 	"strconv"
 	"strings"
 )
 
+// statuses of api responses, an error code is in the response field of statusError
+const (
+	statusError = 0
+	statusOk    = 1
+)
+
 type createResponse struct {
 	Status   int    `json:"status"`
 	Response string `json:"response"`
 }
 
-func SmartCaptchaCreateTask(clickImageUrl string, taskImageUrl string) string {
+// api error codes
+var errorCodes = map[string]error{
+	"CAPCHA_NOT_READY":         captcha.ErrNotReady,
+	"CAPTCHA_NOT_READY":        captcha.ErrNotReady,
+	"ERROR_ZERO_BALANCE":       captcha.ErrNoBalance,
+	"ERROR_KEY_DOES_NOT_EXIST": captcha.ErrNoBalance,
+	"ERROR_WRONG_USER_KEY":     captcha.ErrNoBalance,
+	"ERROR_CAPTCHA_UNSOLVABLE": captcha.ErrUnsolvable,
+}
+
+// Solver is the capsola.cloud SmartCaptcha solver
+type Solver struct{}
+
+func init() {
+	captcha.Register(Solver{})
+}
+
+func (Solver) Name() string {
+	return "capsola"
+}
+
+func (Solver) Submit(task captcha.Task) (string, error) {
+	if task.Type != captcha.TaskSilhouette {
+		return "", fmt.Errorf("capsola: unsupported task type `%v`", task.Type)
+	}
+
+	if os.Getenv("CAPSOLA_API_KEY") == "" {
+		return "", fmt.Errorf("%w: CAPSOLA_API_KEY is not set", captcha.ErrNoBalance)
+	}
+
 	res, err := createTask(map[string]string{
 		"type":  "SmartCaptcha",
-		"click": getImageBase64(clickImageUrl),
-		"task":  getImageBase64(taskImageUrl),
+		"click": base64.StdEncoding.EncodeToString(task.Image),
+		"task":  base64.StdEncoding.EncodeToString(task.Instruction),
 	})
 
 	if err != nil {
-		log.Fatal(err)
+		return "", err
 	}
 
-	var data createResponse
+	data, err := parseResponse(res)
 
-	json.Unmarshal([]byte(res), &data)
+	if err != nil {
+		return "", err
+	}
+
+	if data.Response == "" {
+		return "", fmt.Errorf("capsola: empty task id")
+	}
 
-	return data.Response
+	return data.Response, nil
 }
 
-func SmartCaptchaGetSolution(task_id string) []geometry.Point {
+func (Solver) Poll(taskId string) (captcha.Solution, error) {
 	res, err := getResult(map[string]string{
-		"id": task_id,
+		"id": taskId,
 	})
 
 	if err != nil {
-		log.Fatal(err)
+		return captcha.Solution{}, err
+	}
+
+	data, err := parseResponse(res)
+
+	if err != nil {
+		return captcha.Solution{}, err
 	}
 
+	points, err := parseCoords(data.Response)
+
+	if err != nil {
+		return captcha.Solution{}, err
+	}
+
+	return captcha.Solution{
+		Points: points,
+	}, nil
+}
+
+// ReportBad is not supported by the capsola api
+func (Solver) ReportBad(taskId string) error {
+	return nil
+}
+
+// parseResponse maps error statuses to typed errors
+func parseResponse(res string) (createResponse, error) {
 	var data createResponse
 
-	json.Unmarshal([]byte(res), &data)
+	if err := json.Unmarshal([]byte(res), &data); err != nil {
+		return data, fmt.Errorf("capsola: bad response `%v`: %w", res, err)
+	}
+
+	if data.Status == statusOk {
+		return data, nil
+	}
+
+	code := strings.TrimSpace(data.Response)
+
+	if err, ok := errorCodes[code]; ok {
+		return data, err
+	}
+
+	return data, fmt.Errorf("capsola: error status %v: %v", data.Status, code)
+}
+
+// parseCoords parses `coordinates:x=10,y=20;x=30,y=40` payload
+func parseCoords(response string) ([]geometry.Point, error) {
+	badPayload := fmt.Errorf("%w: capsola payload `%v`", captcha.ErrBadSolution, response)
 
 	// [start] parse coords str
-	coordsStr := strings.Split(data.Response, ":")[1]
-	coordsStrPairs := strings.Split(coordsStr, ";")
+	prefix, coordsStr, ok := strings.Cut(response, ":")
+
+	if !ok || prefix != "coordinates" || coordsStr == "" {
+		return nil, badPayload
+	}
+
+	coordsStrPairs := strings.Split(strings.Trim(coordsStr, ";"), ";")
 	coordsList := []geometry.Point{}
 
 	for i := 0; i < len(coordsStrPairs); i++ {
-		pair := strings.Split(coordsStrPairs[i], ",")
+		values := map[string]float64{}
+
+		for _, part := range strings.Split(coordsStrPairs[i], ",") {
+			name, valueStr, ok := strings.Cut(strings.TrimSpace(part), "=")
+			value, err := strconv.ParseFloat(valueStr, 64)
 
-		X, _ := strconv.ParseFloat(strings.Split(pair[0], "=")[1], 64)
-		Y, _ := strconv.ParseFloat(strings.Split(pair[1], "=")[1], 64)
+			if !ok || err != nil || value < 0 {
+				return nil, badPayload
+			}
+
+			values[name] = value
+		}
+
+		X, hasX := values["x"]
+		Y, hasY := values["y"]
+
+		if !hasX || !hasY {
+			return nil, badPayload
+		}
 
 		point := geometry.Point{
 			X: X,
@@ -68,31 +171,5 @@ func SmartCaptchaGetSolution(task_id string) []geometry.Point {
 	}
 	// [end]
 
-	return coordsList
-}
-
-func getImageBase64(pathOrUrl string) string {
-	var data []byte
-	var err error
-
-	if strings.HasPrefix(pathOrUrl, "http://") || strings.HasPrefix(pathOrUrl, "https://") {
-		resp, err := http.Get(pathOrUrl)
-		if err != nil {
-			log.Fatalf("Ошибка загрузки изображения по URL: %v", err)
-		}
-		defer resp.Body.Close()
-
-		data, err = io.ReadAll(resp.Body)
-		if err != nil {
-			log.Fatalf("Ошибка чтения данных из ответа: %v", err)
-		}
-	} else {
-		// Читаем из локального файла
-		data, err = os.ReadFile(pathOrUrl)
-		if err != nil {
-			log.Fatalf("Ошибка чтения локального файла: %v", err)
-		}
-	}
-
-	return base64.StdEncoding.EncodeToString(data)
+	return coordsList, nil
 }
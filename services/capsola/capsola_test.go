@@ -0,0 +1,88 @@
+package capsola
+
+import (
+	"errors"
+	"parser/services/captcha"
+	"parser/services/geometry"
+	"slices"
+	"testing"
+)
+
+// errUntyped marks responses that must fail without a typed error
+var errUntyped = errors.New("untyped")
+
+func TestParseResponse(t *testing.T) {
+	tests := []struct {
+		name     string
+		res      string
+		expected error // nil - ok, errUntyped - an error of no typed kind
+	}{
+		{"ok", `{"status":1,"response":"12345"}`, nil},
+		{"not ready", `{"status":0,"response":"CAPCHA_NOT_READY"}`, captcha.ErrNotReady},
+		{"not ready spelled right", `{"status":0,"response":"CAPTCHA_NOT_READY"}`, captcha.ErrNotReady},
+		{"zero balance", `{"status":0,"response":"ERROR_ZERO_BALANCE"}`, captcha.ErrNoBalance},
+		{"wrong key", `{"status":0,"response":" ERROR_WRONG_USER_KEY "}`, captcha.ErrNoBalance},
+		{"missing key", `{"status":0,"response":"ERROR_KEY_DOES_NOT_EXIST"}`, captcha.ErrNoBalance},
+		{"unsolvable", `{"status":0,"response":"ERROR_CAPTCHA_UNSOLVABLE"}`, captcha.ErrUnsolvable},
+		{"unknown code", `{"status":0,"response":"ERROR_SOMETHING"}`, errUntyped},
+		{"not json", `<html>502 Bad Gateway</html>`, errUntyped},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data, err := parseResponse(test.res)
+
+			switch {
+			case test.expected == nil:
+				if err != nil || data.Response != "12345" {
+					t.Errorf("expected task id 12345, got %q (%v)", data.Response, err)
+				}
+			case test.expected == errUntyped:
+				if err == nil || errors.Is(err, captcha.ErrNotReady) || errors.Is(err, captcha.ErrNoBalance) || errors.Is(err, captcha.ErrUnsolvable) {
+					t.Errorf("expected an untyped error, got %v", err)
+				}
+			case !errors.Is(err, test.expected):
+				t.Errorf("expected %v, got %v", test.expected, err)
+			}
+		})
+	}
+}
+
+func TestParseCoords(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		expected []geometry.Point // nil - bad payload
+	}{
+		{"single point", "coordinates:x=10,y=20", []geometry.Point{{X: 10, Y: 20}}},
+		{"points", "coordinates:x=10,y=20;x=30.5,y=40;", []geometry.Point{{X: 10, Y: 20}, {X: 30.5, Y: 40}}},
+		{"spaces", "coordinates:x=10, y=20", []geometry.Point{{X: 10, Y: 20}}},
+		{"missing prefix", "x=10,y=20", nil},
+		{"wrong prefix", "coords:x=10,y=20", nil},
+		{"empty coordinates", "coordinates:", nil},
+		{"x without y", "coordinates:x=10", nil},
+		{"y without x", "coordinates:x=10,y=20;y=30", nil},
+		{"negative", "coordinates:x=-10,y=20", nil},
+		{"not a number", "coordinates:x=ten,y=20", nil},
+		{"no value", "coordinates:x=10,y=", nil},
+		{"no separator", "coordinates:x10,y=20", nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			points, err := parseCoords(test.response)
+
+			if test.expected == nil {
+				if !errors.Is(err, captcha.ErrBadSolution) {
+					t.Errorf("expected a bad solution error, got %v (%v)", err, points)
+				}
+
+				return
+			}
+
+			if err != nil || !slices.Equal(points, test.expected) {
+				t.Errorf("expected %v, got %v (%v)", test.expected, points, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package captcha
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// error codes of an empty provider account
+var noBalanceCodes = []string{"ERROR_ZERO_BALANCE", "ERROR_KEY_DOES_NOT_EXIST", "ERROR_WRONG_USER_KEY"}
+
+// TaskApi is a client of the createTask / getTaskResult JSON protocol of Anti-Captcha,
+// also implemented by 2Captcha and RuCaptcha (API v2)
+type TaskApi struct {
+	Url string
+	Key string
+}
+
+type apiResponse struct {
+	ErrorId          int             `json:"errorId"`
+	ErrorCode        string          `json:"errorCode"`
+	ErrorDescription string          `json:"errorDescription"`
+	TaskId           json.Number     `json:"taskId"`
+	Status           string          `json:"status"`
+	Solution         json.RawMessage `json:"solution"`
+}
+
+var apiClient = &http.Client{Timeout: time.Second * 30}
+
+func (a TaskApi) call(method string, request map[string]any) (apiResponse, error) {
+	var data apiResponse
+
+	if a.Key == "" {
+		return data, fmt.Errorf("%w: api key is not set", ErrNoBalance)
+	}
+
+	request["clientKey"] = a.Key
+	body, err := json.Marshal(request)
+
+	if err != nil {
+		return data, err
+	}
+
+	resp, err := apiClient.Post(strings.TrimRight(a.Url, "/")+"/"+method, "application/json", bytes.NewReader(body))
+
+	if err != nil {
+		return data, err
+	}
+
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return data, fmt.Errorf("%v: status %v: %w", method, resp.StatusCode, err)
+	}
+
+	if data.ErrorId != 0 {
+		for _, code := range noBalanceCodes {
+			if data.ErrorCode == code {
+				return data, fmt.Errorf("%w: %v", ErrNoBalance, data.ErrorCode)
+			}
+		}
+
+		return data, fmt.Errorf("%v: %v %v", method, data.ErrorCode, data.ErrorDescription)
+	}
+
+	return data, nil
+}
+
+// CreateTask submits the task object and returns the task id
+func (a TaskApi) CreateTask(task map[string]any) (string, error) {
+	data, err := a.call("createTask", map[string]any{"task": task})
+
+	if err != nil {
+		return "", err
+	}
+
+	return data.TaskId.String(), nil
+}
+
+// GetTaskResult decodes the solution object, ErrNotReady is returned while the task is processing
+func (a TaskApi) GetTaskResult(taskId string, solution any) error {
+	data, err := a.call("getTaskResult", map[string]any{"taskId": json.Number(taskId)})
+
+	if err != nil {
+		return err
+	}
+
+	if data.Status != "ready" {
+		return ErrNotReady
+	}
+
+	return json.Unmarshal(data.Solution, solution)
+}
+
+// Report calls a report method (reportIncorrect, reportIncorrectImageCaptcha ...) for the task
+func (a TaskApi) Report(method string, taskId string) error {
+	_, err := a.call(method, map[string]any{"taskId": json.Number(taskId)})
+
+	return err
+}
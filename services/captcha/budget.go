@@ -0,0 +1,201 @@
+package captcha
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"parser/services/config"
+	"parser/services/storage"
+	"sync"
+	"time"
+)
+
+var ErrBudgetExceeded = errors.New("captcha budget exceeded")
+
+// spending of the current day, survives restarts of the parser
+const spendFile = "captcha/spend.json"
+
+// ProviderStats are solve-quality and cost counters of a provider
+type ProviderStats struct {
+	Submitted      int     `json:"submitted"`
+	SubmitFailed   int     `json:"submit_failed"` // tasks the provider did not accept
+	Solved         int     `json:"solved"`        // solutions returned by the provider
+	Wrong          int     `json:"wrong"`         // solutions that did not pass the captcha
+	Failed         int     `json:"failed"`        // timeouts, unsolvable and provider errors of submitted tasks
+	SolveTimeMs    int64   `json:"solve_time_ms"` // total time-to-solve of the solved tasks
+	AvgSolveTimeMs int64   `json:"avg_solve_time_ms"`
+	Cost           float64 `json:"cost"` // estimated by config.CaptchaPrices
+}
+
+// Merge adds counters of other stats to s
+func (s *ProviderStats) Merge(other ProviderStats) {
+	s.Submitted += other.Submitted
+	s.SubmitFailed += other.SubmitFailed
+	s.Solved += other.Solved
+	s.Wrong += other.Wrong
+	s.Failed += other.Failed
+	s.SolveTimeMs += other.SolveTimeMs
+	s.Cost += other.Cost
+
+	if s.Solved > 0 {
+		s.AvgSolveTimeMs = s.SolveTimeMs / int64(s.Solved)
+	}
+}
+
+type daySpend struct {
+	Date string  `json:"date"`
+	Cost float64 `json:"cost"`
+}
+
+var (
+	statsMu sync.Mutex
+	stats   = map[string]*ProviderStats{}
+	runCost float64
+	dayCost *daySpend // loaded from spendFile on first use
+)
+
+// TakeStats returns the provider counters gathered since the previous call and resets them,
+// so they can be merged into the journal with each chunk without double counting
+func TakeStats() map[string]ProviderStats {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+
+	list := map[string]ProviderStats{}
+
+	for name, providerStats := range stats {
+		list[name] = *providerStats
+	}
+
+	stats = map[string]*ProviderStats{}
+
+	return list
+}
+
+func providerStats(name string) *ProviderStats {
+	if stats[name] == nil {
+		stats[name] = &ProviderStats{}
+	}
+
+	return stats[name]
+}
+
+func recordSubmit(provider string) {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+
+	providerStats(provider).Submitted++
+}
+
+// recordSolve counts the solution and charges its estimated price to the run and day budgets
+func recordSolve(provider string, solveTime time.Duration) {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+
+	price := config.CaptchaPrices[provider] / 1000
+	s := providerStats(provider)
+	s.Solved++
+	s.SolveTimeMs += solveTime.Milliseconds()
+	s.AvgSolveTimeMs = s.SolveTimeMs / int64(s.Solved)
+	s.Cost += price
+
+	if price == 0 {
+		return
+	}
+
+	runCost += price
+	spend := loadDaySpend()
+	spend.Cost += price
+
+	if err := storage.WriteFile(spendFile, spend); err != nil {
+		log.Printf("[WARN] Can't save captcha spending: %v", err)
+	}
+}
+
+func recordSubmitFailure(provider string) {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+
+	providerStats(provider).SubmitFailed++
+}
+
+func recordFailure(provider string) {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+
+	providerStats(provider).Failed++
+}
+
+func recordWrong(provider string) {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+
+	providerStats(provider).Wrong++
+}
+
+// loadDaySpend returns spending of today, statsMu must be held
+func loadDaySpend() *daySpend {
+	today := time.Now().Format(time.DateOnly)
+
+	if dayCost == nil {
+		dayCost = &daySpend{}
+
+		if storage.Exists(spendFile) {
+			if data, err := storage.ReadFile(spendFile); err == nil {
+				json.Unmarshal([]byte(data), dayCost)
+			}
+		}
+	}
+
+	if dayCost.Date != today {
+		dayCost.Date = today
+		dayCost.Cost = 0
+	}
+
+	return dayCost
+}
+
+// CheckBudget returns ErrBudgetExceeded when the run (config.CaptchaRunBudget) or the day
+// (config.CaptchaDayBudget) budget is spent, 0 budget is unlimited
+func CheckBudget() error {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+
+	if config.CaptchaRunBudget > 0 && runCost >= config.CaptchaRunBudget {
+		return fmt.Errorf("%w: run spent %.2f of %.2f", ErrBudgetExceeded, runCost, config.CaptchaRunBudget)
+	}
+
+	if spend := loadDaySpend(); config.CaptchaDayBudget > 0 && spend.Cost >= config.CaptchaDayBudget {
+		return fmt.Errorf("%w: %v spent %.2f of %.2f", ErrBudgetExceeded, spend.Date, spend.Cost, config.CaptchaDayBudget)
+	}
+
+	return nil
+}
+
+// WaitBudget pauses scraping while the day budget is spent (until the next day). An exceeded
+// run budget can't be waited out, its error is returned.
+func WaitBudget() error {
+	for {
+		err := CheckBudget()
+
+		if err == nil {
+			return nil
+		}
+
+		if config.CaptchaRunBudget > 0 && runSpent() {
+			return err
+		}
+
+		now := time.Now()
+		tomorrow := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())
+		log.Printf("[ALERT] %v, pause until %v", err, tomorrow.Format(time.DateTime))
+		time.Sleep(time.Until(tomorrow))
+	}
+}
+
+func runSpent() bool {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+
+	return runCost >= config.CaptchaRunBudget
+}
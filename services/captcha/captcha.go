@@ -0,0 +1,299 @@
+/**
+ * package captcha
+ *
+ * Captcha solving providers. Provider packages (capsola, rucaptcha, anticaptcha) implement
+ * CaptchaSolver and register themselves, Solve tries them in the config.CaptchaSolvers order.
+ */
+
+package captcha
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"parser/services/config"
+	"parser/services/geometry"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// task types
+const (
+	TaskSilhouette = "silhouette" // SmartCaptcha: click the image in the order of the instruction silhouettes
+	TaskText       = "text"       // type the text of the image
+	TaskSlider     = "slider"     // click the position of the image where the puzzle is assembled, X is the slider offset
+)
+
+type Task struct {
+	Type        string
+	Image       []byte // image to click on
+	Instruction []byte // task image (silhouettes order)
+	Comment     string // instruction for workers of human-powered providers
+}
+
+type Solution struct {
+	Provider string
+	TaskId   string
+	Points   []geometry.Point // click points relative to the image
+	Text     string           // answer of the text task
+}
+
+var (
+	ErrNotReady    = errors.New("captcha is not solved yet")
+	ErrNoBalance   = errors.New("provider balance is exhausted")
+	ErrTimeout     = errors.New("captcha solve timeout")
+	ErrUnsolvable  = errors.New("captcha is unsolvable")
+	ErrBadSolution = errors.New("bad solution payload")
+)
+
+// CaptchaSolver is implemented by every captcha provider
+type CaptchaSolver interface {
+	// Name is the provider name used in config and stats
+	Name() string
+
+	// Submit creates the provider task and returns its id
+	Submit(task Task) (string, error)
+
+	// Poll returns the solution or ErrNotReady while the task is in progress
+	Poll(taskId string) (Solution, error)
+
+	// ReportBad reports a wrong solution (the captcha was not passed)
+	ReportBad(taskId string) error
+}
+
+var (
+	solversMu   sync.RWMutex
+	solvers     = map[string]CaptchaSolver{}
+	disabled    = map[string]bool{}     // providers without balance
+	solverOrder = config.CaptchaSolvers // fallback order of Solve
+)
+
+// Register makes the solver available by its name. Provider packages call it from init().
+func Register(solver CaptchaSolver) {
+	solversMu.Lock()
+	defer solversMu.Unlock()
+
+	solvers[solver.Name()] = solver
+}
+
+// Get returns registered solver by name
+func Get(name string) (CaptchaSolver, error) {
+	solversMu.RLock()
+	defer solversMu.RUnlock()
+
+	solver, ok := solvers[name]
+
+	if !ok {
+		return nil, fmt.Errorf("unknown captcha solver `%v` (available: %v)", name, strings.Join(registeredNames(), ", "))
+	}
+
+	return solver, nil
+}
+
+// SetOrder overrides the config.CaptchaSolvers fallback order (tests use fake solvers)
+func SetOrder(names string) {
+	solversMu.Lock()
+	defer solversMu.Unlock()
+
+	solverOrder = names
+}
+
+func registeredNames() []string {
+	list := []string{}
+
+	for name := range solvers {
+		list = append(list, name)
+	}
+
+	sort.Strings(list)
+
+	return list
+}
+
+// SolveWith submits the task and polls the solver until the solution is ready or
+// config.CaptchaTimeout is exceeded. The poll interval starts from config.CaptchaPollInterval
+// and grows by half up to config.CaptchaPollMaxInterval. The solution is validated against the task.
+func SolveWith(solver CaptchaSolver, task Task) (solution Solution, err error) {
+	startTime := time.Now()
+	taskId, err := solver.Submit(task)
+
+	if err != nil {
+		recordSubmitFailure(solver.Name())
+		return Solution{}, err
+	}
+
+	recordSubmit(solver.Name())
+
+	defer func() {
+		if err != nil {
+			recordFailure(solver.Name())
+		} else {
+			recordSolve(solver.Name(), time.Since(startTime))
+		}
+	}()
+
+	deadline := time.Now().Add(config.CaptchaTimeout)
+	interval := config.CaptchaPollInterval
+
+	for {
+		wait := min(interval, time.Until(deadline))
+
+		if wait <= 0 {
+			return Solution{}, ErrTimeout
+		}
+
+		time.Sleep(wait)
+		interval = min(interval*3/2, config.CaptchaPollMaxInterval)
+
+		solution, err := solver.Poll(taskId)
+
+		if errors.Is(err, ErrNotReady) {
+			continue
+		}
+
+		if err != nil {
+			return Solution{}, err
+		}
+
+		solution.Provider = solver.Name()
+		solution.TaskId = taskId
+
+		if err := validate(task, solution); err != nil {
+			return Solution{}, err
+		}
+
+		return solution, nil
+	}
+}
+
+// validate checks that the text task has an answer and the solution of the other tasks has
+// points inside the image (when its size is known)
+func validate(task Task, solution Solution) error {
+	if task.Type == TaskText {
+		if strings.TrimSpace(solution.Text) == "" {
+			return fmt.Errorf("%w: no text", ErrBadSolution)
+		}
+
+		return nil
+	}
+
+	if len(solution.Points) == 0 {
+		return fmt.Errorf("%w: no points", ErrBadSolution)
+	}
+
+	imageConfig, _, err := image.DecodeConfig(bytes.NewReader(task.Image))
+
+	if err != nil {
+		return nil
+	}
+
+	for _, point := range solution.Points {
+		if point.X < 0 || point.Y < 0 || point.X > float64(imageConfig.Width) || point.Y > float64(imageConfig.Height) {
+			return fmt.Errorf("%w: point %v outside of the image %vx%v", ErrBadSolution, point, imageConfig.Width, imageConfig.Height)
+		}
+	}
+
+	return nil
+}
+
+// Solve solves the task with the providers of config.CaptchaSolvers in order: when a
+// provider fails the next one is used, a provider out of balance is skipped until restart.
+// ErrBudgetExceeded is returned without submitting when the captcha budget is spent.
+func Solve(task Task) (Solution, error) {
+	var lastErr error = errors.New("no captcha solver configured")
+
+	if err := CheckBudget(); err != nil {
+		return Solution{}, err
+	}
+
+	solversMu.RLock()
+	names := strings.Split(solverOrder, ",")
+	solversMu.RUnlock()
+
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		solver, err := Get(name)
+
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		if isDisabled(name) {
+			continue
+		}
+
+		solution, err := SolveWith(solver, task)
+
+		if err == nil {
+			return solution, nil
+		}
+
+		log.Printf("[WARN] Captcha solver %v: %v", name, err)
+		lastErr = err
+
+		if errors.Is(err, ErrNoBalance) {
+			disable(name)
+		}
+	}
+
+	return Solution{}, lastErr
+}
+
+// ReportBad counts a wrong solution and reports it to its provider
+func ReportBad(solution Solution) error {
+	recordWrong(solution.Provider)
+	solver, err := Get(solution.Provider)
+
+	if err != nil {
+		return err
+	}
+
+	return solver.ReportBad(solution.TaskId)
+}
+
+func isDisabled(name string) bool {
+	solversMu.RLock()
+	defer solversMu.RUnlock()
+
+	return disabled[name]
+}
+
+func disable(name string) {
+	solversMu.Lock()
+	defer solversMu.Unlock()
+
+	log.Printf("[ALERT] Captcha solver %v is out of balance, disabled", name)
+	disabled[name] = true
+}
+
+// LoadImage reads the image by url or local path
+func LoadImage(pathOrUrl string) ([]byte, error) {
+	if !strings.HasPrefix(pathOrUrl, "http://") && !strings.HasPrefix(pathOrUrl, "https://") {
+		return os.ReadFile(pathOrUrl)
+	}
+
+	resp, err := http.Get(pathOrUrl)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("image %v: status %v", pathOrUrl, resp.StatusCode)
+	}
+
+	return io.ReadAll(resp.Body)
+}
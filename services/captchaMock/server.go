@@ -0,0 +1,279 @@
+package captchaMock
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"parser/services/geometry"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// captcha variants of the server
+const (
+	VariantSilhouette = "silhouette"
+	VariantText       = "text"
+	VariantSlider     = "slider"
+)
+
+// size of the click image
+const (
+	ImageWidth  = 300
+	ImageHeight = 200
+)
+
+// cookie of the passed captcha
+const passedCookie = "spravka"
+
+// Server serves /search/ pages. Until the captcha is passed they redirect to /showcaptcha:
+// the "I am not a robot" button (#js-button) and then the advanced task of the Variant.
+// Points are checked with Tolerance pixels.
+type Server struct {
+	*httptest.Server
+
+	Variant   string
+	Points    []geometry.Point // expected clicks of the silhouette task, X of the first one is the slider offset
+	Text      string           // expected answer of the text task
+	Tolerance float64
+
+	mu     sync.Mutex
+	checks int
+	passed int
+}
+
+// NewServer starts the server with the variant task
+func NewServer(variant string, points []geometry.Point, text string) *Server {
+	s := &Server{
+		Variant:   variant,
+		Points:    points,
+		Text:      text,
+		Tolerance: 10,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/search/", s.search)
+	mux.HandleFunc("/showcaptcha", s.showCaptcha)
+	mux.HandleFunc("/showcaptcha/check", s.check)
+	mux.HandleFunc("/captcha/image.png", s.image(ImageWidth, ImageHeight, color.RGBA{R: 200, G: 220, B: 240, A: 255}))
+	mux.HandleFunc("/captcha/task.png", s.image(150, 40, color.RGBA{R: 240, G: 220, B: 200, A: 255}))
+	s.Server = httptest.NewServer(mux)
+
+	return s
+}
+
+// SearchUrl returns the url of a search page
+func (s *Server) SearchUrl(text string) string {
+	return s.URL + "/search/?text=" + url.QueryEscape(text)
+}
+
+// Stats returns the number of submitted and passed captcha tasks
+func (s *Server) Stats() (checks int, passed int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.checks, s.passed
+}
+
+func (s *Server) search(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(passedCookie); err != nil || cookie.Value != "1" {
+		http.Redirect(w, r, "/showcaptcha?retpath="+url.QueryEscape(r.URL.RequestURI()), http.StatusFound)
+		return
+	}
+
+	render(w, searchPage, map[string]string{"Text": r.URL.Query().Get("text")})
+}
+
+func (s *Server) showCaptcha(w http.ResponseWriter, r *http.Request) {
+	data := map[string]string{
+		"Retpath": r.URL.Query().Get("retpath"),
+		"Width":   strconv.Itoa(ImageWidth),
+		"Height":  strconv.Itoa(ImageHeight),
+	}
+
+	switch {
+	case r.URL.Query().Get("step") != "advanced":
+		render(w, checkboxPage, data)
+	case s.Variant == VariantText:
+		render(w, textPage, data)
+	case s.Variant == VariantSlider:
+		render(w, sliderPage, data)
+	default:
+		render(w, silhouettePage, data)
+	}
+}
+
+func (s *Server) check(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	retpath := r.Form.Get("retpath")
+
+	if !strings.HasPrefix(retpath, "/") {
+		retpath = "/search/"
+	}
+
+	s.mu.Lock()
+	s.checks++
+	passed := s.isPassed(r.Form.Get("points"), r.Form.Get("rep"), r.Form.Get("offset"))
+
+	if passed {
+		s.passed++
+	}
+	s.mu.Unlock()
+
+	if !passed {
+		http.Redirect(w, r, "/showcaptcha?step=advanced&retpath="+url.QueryEscape(retpath), http.StatusFound)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{Name: passedCookie, Value: "1", Path: "/"})
+	http.Redirect(w, r, retpath, http.StatusFound)
+}
+
+// isPassed checks the answer of the variant, points are "x,y;x,y" clicks in the image,
+// offset is the slider position in pixels of the image
+func (s *Server) isPassed(points string, text string, offset string) bool {
+	switch s.Variant {
+	case VariantText:
+		return strings.EqualFold(strings.TrimSpace(text), s.Text)
+	case VariantSlider:
+		x, err := strconv.ParseFloat(offset, 64)
+
+		return err == nil && len(s.Points) > 0 && math.Abs(x-s.Points[0].X) <= s.Tolerance
+	}
+
+	clicks := []geometry.Point{}
+
+	for _, pair := range strings.Split(points, ";") {
+		xStr, yStr, ok := strings.Cut(pair, ",")
+		x, errX := strconv.ParseFloat(xStr, 64)
+		y, errY := strconv.ParseFloat(yStr, 64)
+
+		if !ok || errX != nil || errY != nil {
+			return false
+		}
+
+		clicks = append(clicks, geometry.Point{X: x, Y: y})
+	}
+
+	if len(clicks) != len(s.Points) {
+		return false
+	}
+
+	for i, click := range clicks {
+		if math.Hypot(click.X-s.Points[i].X, click.Y-s.Points[i].Y) > s.Tolerance {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (s *Server) image(width int, height int, fill color.Color) http.HandlerFunc {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			img.Set(x, y, fill)
+		}
+	}
+
+	var buf bytes.Buffer
+	png.Encode(&buf, img)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(buf.Bytes())
+	}
+}
+
+func render(w http.ResponseWriter, page *template.Template, data map[string]string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	if err := page.Execute(w, data); err != nil {
+		http.Error(w, fmt.Sprint(err), http.StatusInternalServerError)
+	}
+}
+
+var searchPage = template.Must(template.New("search").Parse(`<!DOCTYPE html>
+<html><head><title>{{.Text}} — поиск</title></head>
+<body><div class="content__left"><ul id="search-result"></ul></div></body></html>`))
+
+var checkboxPage = template.Must(template.New("checkbox").Parse(`<!DOCTYPE html>
+<html><head><title>Вы не робот?</title></head>
+<body style="margin:0">
+<form class="CheckboxCaptcha" method="get" action="/showcaptcha">
+	<input type="hidden" name="retpath" value="{{.Retpath}}">
+	<input type="hidden" name="step" value="advanced">
+	<input id="js-button" class="CheckboxCaptcha-Button" type="submit" value="Я не робот">
+</form>
+</body></html>`))
+
+var silhouettePage = template.Must(template.New("silhouette").Parse(`<!DOCTYPE html>
+<html><head><title>Вы не робот?</title></head>
+<body style="margin:0">
+<form id="captcha" class="AdvancedCaptcha" method="post" action="/showcaptcha/check">
+	<input type="hidden" name="retpath" value="{{.Retpath}}">
+	<input type="hidden" name="points" id="points" value="">
+	<div class="AdvancedCaptcha-ImageWrapper"><img src="/captcha/image.png" width="{{.Width}}" height="{{.Height}}" style="display:block"></div>
+	<div class="AdvancedCaptcha-SilhouetteTask"><img src="/captcha/task.png"></div>
+	<div class="CaptchaButton-ProgressWrapper" onclick="document.getElementById('captcha').submit()">Отправить</div>
+</form>
+<script>
+	var img = document.querySelector('.AdvancedCaptcha-ImageWrapper img');
+	img.addEventListener('click', function(e) {
+		var r = img.getBoundingClientRect();
+		var points = document.getElementById('points');
+		points.value += (points.value ? ';' : '') + Math.round(e.clientX - r.left) + ',' + Math.round(e.clientY - r.top);
+	});
+</script>
+</body></html>`))
+
+var textPage = template.Must(template.New("text").Parse(`<!DOCTYPE html>
+<html><head><title>Вы не робот?</title></head>
+<body style="margin:0">
+<form id="captcha" class="AdvancedCaptcha" method="post" action="/showcaptcha/check">
+	<input type="hidden" name="retpath" value="{{.Retpath}}">
+	<div class="AdvancedCaptcha-View"><img src="/captcha/image.png" width="{{.Width}}" height="{{.Height}}" style="display:block"></div>
+	<input class="Textinput-Control" name="rep" autocomplete="off">
+	<div class="CaptchaButton-ProgressWrapper" onclick="document.getElementById('captcha').submit()">Отправить</div>
+</form>
+</body></html>`))
+
+// sliderPage moves the button with the mouse along the track, the offset is the share of the
+// track passed by the button in pixels of the image
+var sliderPage = template.Must(template.New("slider").Parse(`<!DOCTYPE html>
+<html><head><title>Вы не робот?</title></head>
+<body style="margin:0">
+<form id="captcha" class="AdvancedCaptcha AdvancedCaptcha_kaleidoscope" method="post" action="/showcaptcha/check">
+	<input type="hidden" name="retpath" value="{{.Retpath}}">
+	<input type="hidden" name="offset" id="offset" value="0">
+	<div class="AdvancedCaptcha-ImageWrapper"><img src="/captcha/image.png" width="{{.Width}}" height="{{.Height}}" style="display:block"></div>
+	<div class="CaptchaSlider" style="position:relative;width:{{.Width}}px;height:40px;background:#eee">
+		<div class="CaptchaSlider-Button" style="position:absolute;left:0;top:0;width:40px;height:40px;background:#888"></div>
+	</div>
+	<div class="CaptchaButton-ProgressWrapper" onclick="document.getElementById('captcha').submit()">Отправить</div>
+</form>
+<script>
+	var track = document.querySelector('.CaptchaSlider');
+	var button = document.querySelector('.CaptchaSlider-Button');
+	var img = document.querySelector('.AdvancedCaptcha-ImageWrapper img');
+	var startX = null;
+
+	button.addEventListener('mousedown', function(e) { startX = e.clientX; });
+	document.addEventListener('mousemove', function(e) {
+		if (startX === null) return;
+		var free = track.clientWidth - button.offsetWidth;
+		var left = Math.min(Math.max(e.clientX - startX, 0), free);
+		button.style.left = left + 'px';
+		document.getElementById('offset').value = Math.round(left / free * img.naturalWidth);
+	});
+	document.addEventListener('mouseup', function() { startX = null; });
+</script>
+</body></html>`))
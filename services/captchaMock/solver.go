@@ -0,0 +1,115 @@
+/**
+ * package captchaMock
+ *
+ * Offline captcha testing. Solver is an in-process captcha.CaptchaSolver returning prepared
+ * solutions, Server is a local search site that redirects to a showcaptcha flow with the
+ * Yandex SmartCaptcha DOM classes. Together they run the chromedp captcha path end to end
+ * without live Yandex and paid providers.
+ */
+
+package captchaMock
+
+import (
+	"fmt"
+	"parser/services/captcha"
+	"parser/services/geometry"
+	"sync"
+)
+
+// Solver is a fake provider. Register it with captcha.Register and select it with captcha.SetOrder.
+type Solver struct {
+	SolverName string
+	Points     []geometry.Point // solution of the click and slider tasks
+	Text       string           // solution of the text task
+	NotReady   int              // polls answered with captcha.ErrNotReady before the solution
+
+	mu        sync.Mutex
+	tasks     map[string]captcha.Task
+	polls     map[string]int
+	reported  []string
+	submitted int
+}
+
+// NewSolver returns a fake provider solving every task with the points and text
+func NewSolver(name string, points []geometry.Point, text string) *Solver {
+	return &Solver{
+		SolverName: name,
+		Points:     points,
+		Text:       text,
+	}
+}
+
+func (s *Solver) Name() string {
+	return s.SolverName
+}
+
+func (s *Solver) Submit(task captcha.Task) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(task.Image) == 0 {
+		return "", fmt.Errorf("%v: empty image", s.SolverName)
+	}
+
+	if s.tasks == nil {
+		s.tasks = map[string]captcha.Task{}
+		s.polls = map[string]int{}
+	}
+
+	s.submitted++
+	taskId := fmt.Sprint(s.submitted)
+	s.tasks[taskId] = task
+
+	return taskId, nil
+}
+
+func (s *Solver) Poll(taskId string) (captcha.Solution, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.tasks[taskId]; !ok {
+		return captcha.Solution{}, fmt.Errorf("%v: unknown task %v", s.SolverName, taskId)
+	}
+
+	s.polls[taskId]++
+
+	if s.polls[taskId] <= s.NotReady {
+		return captcha.Solution{}, captcha.ErrNotReady
+	}
+
+	return captcha.Solution{
+		Points: append([]geometry.Point{}, s.Points...),
+		Text:   s.Text,
+	}, nil
+}
+
+func (s *Solver) ReportBad(taskId string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.reported = append(s.reported, taskId)
+
+	return nil
+}
+
+// Tasks returns submitted tasks in order
+func (s *Solver) Tasks() []captcha.Task {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := []captcha.Task{}
+
+	for i := 1; i <= s.submitted; i++ {
+		list = append(list, s.tasks[fmt.Sprint(i)])
+	}
+
+	return list
+}
+
+// Reported returns ids of the tasks reported as wrong
+func (s *Solver) Reported() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string{}, s.reported...)
+}
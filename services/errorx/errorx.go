@@ -0,0 +1,118 @@
+/**
+ * package errorx
+ *
+ * Typed errors of the parsing pipeline and retry policies per error kind.
+ */
+
+package errorx
+
+import (
+	"errors"
+	"fmt"
+	"parser/services/config"
+	"time"
+)
+
+type Kind string
+
+const (
+	KindCaptcha   Kind = "captcha"    // captcha page or unsolved captcha
+	KindBan       Kind = "ban"        // access denied / too many requests
+	KindProxyDead Kind = "proxy_dead" // proxy does not respond or no alive proxy left
+	KindNetwork   Kind = "network"    // transport error or bad status without proxy fault
+	KindParse     Kind = "parse"      // page could not be parsed
+	KindNoSession Kind = "no_session" // no pooled session got ready in time
+)
+
+type Error struct {
+	Kind Kind
+	Op   string
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%v: %v: %v", e.Kind, e.Op, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func New(kind Kind, op string, err error) *Error {
+	return &Error{
+		Kind: kind,
+		Op:   op,
+		Err:  err,
+	}
+}
+
+// Errorf creates typed error with formatted message
+func Errorf(kind Kind, op string, format string, args ...any) *Error {
+	return New(kind, op, fmt.Errorf(format, args...))
+}
+
+// KindOf returns kind of the typed error in the chain or "" for untyped errors
+func KindOf(err error) Kind {
+	var typed *Error
+
+	if errors.As(err, &typed) {
+		return typed.Kind
+	}
+
+	return ""
+}
+
+func Is(err error, kind Kind) bool {
+	return KindOf(err) == kind
+}
+
+// Wrap types an untyped error with the kind, typed errors are returned as is
+func Wrap(kind Kind, op string, err error) error {
+	if err == nil || KindOf(err) != "" {
+		return err
+	}
+
+	return New(kind, op, err)
+}
+
+// RetryPolicy is the number of retries of an error kind and the exponential backoff between them
+type RetryPolicy struct {
+	Attempts   int
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+var Policies = map[Kind]RetryPolicy{
+	KindCaptcha:   {Attempts: config.RetryCaptchaAttempts, Backoff: config.RetryCaptchaBackoff, MaxBackoff: config.RetryMaxBackoff},
+	KindBan:       {Attempts: config.RetryBanAttempts, Backoff: config.RetryBanBackoff, MaxBackoff: config.RetryMaxBackoff},
+	KindProxyDead: {Attempts: config.RetryProxyDeadAttempts, Backoff: config.RetryProxyDeadBackoff, MaxBackoff: config.RetryMaxBackoff},
+	KindNetwork:   {Attempts: config.RetryNetworkAttempts, Backoff: config.RetryNetworkBackoff, MaxBackoff: config.RetryMaxBackoff},
+	KindParse:     {Attempts: config.RetryParseAttempts, Backoff: config.RetryParseBackoff, MaxBackoff: config.RetryMaxBackoff},
+	KindNoSession: {Attempts: config.RetryNoSessionAttempts, Backoff: config.RetryNoSessionBackoff, MaxBackoff: config.RetryMaxBackoff},
+}
+
+// PolicyOf returns retry policy of the error kind, untyped errors use the network policy
+func PolicyOf(kind Kind) RetryPolicy {
+	policy, ok := Policies[kind]
+
+	if !ok {
+		return Policies[KindNetwork]
+	}
+
+	return policy
+}
+
+// Delay returns backoff before the retry attempt (starting from 1)
+func (p RetryPolicy) Delay(attempt int) time.Duration {
+	delay := p.Backoff
+
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+
+		if p.MaxBackoff > 0 && delay >= p.MaxBackoff {
+			return p.MaxBackoff
+		}
+	}
+
+	return delay
+}
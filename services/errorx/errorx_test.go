@@ -0,0 +1,115 @@
+package errorx
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestDelay(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   RetryPolicy
+		attempt  int
+		expected time.Duration
+	}{
+		{"first attempt", RetryPolicy{Backoff: time.Second}, 1, time.Second},
+		{"doubled", RetryPolicy{Backoff: time.Second}, 3, time.Second * 4},
+		{"no max", RetryPolicy{Backoff: time.Second}, 8, time.Second * 128},
+		{"below max", RetryPolicy{Backoff: time.Second, MaxBackoff: time.Second * 10}, 4, time.Second * 8},
+		{"capped", RetryPolicy{Backoff: time.Second, MaxBackoff: time.Second * 10}, 5, time.Second * 10},
+		{"capped far", RetryPolicy{Backoff: time.Second, MaxBackoff: time.Second * 10}, 50, time.Second * 10},
+		{"no backoff", RetryPolicy{}, 3, 0},
+	}
+
+	for _, test := range tests {
+		if delay := test.policy.Delay(test.attempt); delay != test.expected {
+			t.Errorf("%v: expected %v, got %v", test.name, test.expected, delay)
+		}
+	}
+}
+
+func TestPolicyOf(t *testing.T) {
+	tests := []struct {
+		kind     Kind
+		expected Kind
+	}{
+		{KindCaptcha, KindCaptcha},
+		{KindBan, KindBan},
+		{KindProxyDead, KindProxyDead},
+		{KindNetwork, KindNetwork},
+		{KindParse, KindParse},
+		{KindNoSession, KindNoSession},
+		{"", KindNetwork},
+		{"unknown", KindNetwork},
+	}
+
+	for _, test := range tests {
+		if policy := PolicyOf(test.kind); policy != Policies[test.expected] {
+			t.Errorf("%q: expected the %v policy, got %+v", test.kind, test.expected, policy)
+		}
+	}
+}
+
+func TestKindOf(t *testing.T) {
+	typed := Errorf(KindBan, "fetch", "status %v", 403)
+
+	tests := []struct {
+		name     string
+		err      error
+		expected Kind
+	}{
+		{"nil", nil, ""},
+		{"untyped", errors.New("timeout"), ""},
+		{"typed", typed, KindBan},
+		{"wrapped by fmt", fmt.Errorf("page 2: %w", typed), KindBan},
+		{"typed cause", New(KindCaptcha, "solve", typed), KindCaptcha},
+	}
+
+	for _, test := range tests {
+		if kind := KindOf(test.err); kind != test.expected {
+			t.Errorf("%v: expected %q, got %q", test.name, test.expected, kind)
+		}
+
+		if test.expected != "" && !Is(test.err, test.expected) {
+			t.Errorf("%v: Is(%v) is false", test.name, test.expected)
+		}
+	}
+}
+
+func TestWrap(t *testing.T) {
+	cause := errors.New("connection reset")
+	typed := Errorf(KindCaptcha, "fetch", "showcaptcha")
+
+	tests := []struct {
+		name     string
+		err      error
+		expected Kind
+	}{
+		{"nil stays nil", nil, ""},
+		{"untyped gets the kind", cause, KindNetwork},
+		{"typed keeps its kind", typed, KindCaptcha},
+		{"wrapped typed keeps its kind", fmt.Errorf("page 1: %w", typed), KindCaptcha},
+	}
+
+	for _, test := range tests {
+		err := Wrap(KindNetwork, "generate session", test.err)
+
+		if test.err == nil {
+			if err != nil {
+				t.Errorf("%v: expected nil, got %v", test.name, err)
+			}
+
+			continue
+		}
+
+		if kind := KindOf(err); kind != test.expected {
+			t.Errorf("%v: expected %q, got %q", test.name, test.expected, kind)
+		}
+
+		if !errors.Is(err, test.err) {
+			t.Errorf("%v: the cause is lost in %v", test.name, err)
+		}
+	}
+}
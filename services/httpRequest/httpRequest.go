@@ -6,18 +6,23 @@ import (
 	"compress/zlib"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/Danny-Dasilva/CycleTLS/cycletls"
 	"github.com/andybalholm/brotli"
 	"io"
 	"io/ioutil"
-	"log"
-	"math/rand"
+	"math"
 	"net/http"
 	"net/url"
+	"parser/services/config"
+	"parser/services/useragent"
 	"strings"
 	"time"
 )
 
+// Timeout limits Get and GetCycleTls requests, tools may change it before the first request
+var Timeout = config.RequestTimeout
+
 func readResponseBody(resp *http.Response) (string, error) {
 	var reader io.Reader = resp.Body
 	defer resp.Body.Close()
@@ -73,13 +78,17 @@ func Get(pageUrl string, options map[string]map[string]string) (string, *http.Re
 
 		client = &http.Client{
 			Transport: transport,
+			Timeout:   Timeout,
 		}
 	} else {
-		client = &http.Client{}
+		client = &http.Client{Timeout: Timeout}
 	}
 
 	resp, err := client.Do(req)
-	defer resp.Body.Close()
+
+	if err != nil {
+		return "", resp, err
+	}
 
 	// read res
 	body, err := readResponseBody(resp)
@@ -91,17 +100,39 @@ func Get(pageUrl string, options map[string]map[string]string) (string, *http.Re
 	return body, resp, err
 }
 
+const cycleTlsErrorPrefix = "Request returned a Syscall Error"
+
+// GetCycleTls loads the page with the "fingerprint" options (userAgent, ja3 and comma separated
+// headerOrder of the session profile), a request without them gets a random profile
 func GetCycleTls(pageUrl string, options *map[string]map[string]string) (string, *cycletls.Response, error) {
 	// send req
 	client := cycletls.Init()
+	profile := useragent.NewFingerprint()
 
 	cycletlsOptions := cycletls.Options{
-		Body:      "",
-		Ja3:       getRandomJA3(),
-		UserAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/125.0.0.0 Safari/537.36",
+		Body:        "",
+		Ja3:         profile.Ja3,
+		UserAgent:   profile.UserAgent,
+		HeaderOrder: profile.HeaderOrder,
+		Timeout:     int(math.Ceil(Timeout.Seconds())), // seconds
 	}
 
 	if options != nil {
+		// session fingerprint
+		fingerprint := (*options)["fingerprint"]
+
+		if fingerprint["userAgent"] != "" {
+			cycletlsOptions.UserAgent = fingerprint["userAgent"]
+		}
+
+		if fingerprint["ja3"] != "" {
+			cycletlsOptions.Ja3 = fingerprint["ja3"]
+		}
+
+		if fingerprint["headerOrder"] != "" {
+			cycletlsOptions.HeaderOrder = strings.Split(fingerprint["headerOrder"], ",")
+		}
+
 		headers, ok := (*options)["headers"]
 
 		if ok {
@@ -121,6 +152,18 @@ func GetCycleTls(pageUrl string, options *map[string]map[string]string) (string,
 		return "", &resp, err
 	}
 
+	// CycleTLS returns transport errors (dead proxy, dns, timeout) as a response
+	// with a made-up status and the error in the body
+	if resp.Status == 0 || strings.HasPrefix(resp.Body, cycleTlsErrorPrefix) {
+		message, details, _ := strings.Cut(resp.Body, "-> \n")
+
+		if message == "" {
+			message = details
+		}
+
+		return "", &resp, errors.New(strings.TrimSpace(message))
+	}
+
 	// read res
 	body := resp.Body
 
@@ -138,13 +181,13 @@ func Post(url string, options map[string]map[string]string) (string, error) {
 	jsonData, err := json.Marshal(data)
 
 	if err != nil {
-		log.Fatalf("Ошибка сериализации JSON: %v", err)
+		return "", fmt.Errorf("ошибка сериализации JSON: %w", err)
 	}
 
 	req, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
 
 	if err != nil {
-		log.Fatalf("Ошибка создания запроса: %v", err)
+		return "", fmt.Errorf("ошибка создания запроса: %w", err)
 	}
 
 	if headersExists {
@@ -154,36 +197,18 @@ func Post(url string, options map[string]map[string]string) (string, error) {
 	}
 
 	// send req
-	client := &http.Client{}
+	client := &http.Client{Timeout: time.Second * 30}
 	resp, err := client.Do(req)
 	if err != nil {
-		log.Fatalf("Ошибка отправки запроса: %v", err)
+		return "", fmt.Errorf("ошибка отправки запроса: %w", err)
 	}
 	defer resp.Body.Close()
 
 	// read res
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		log.Fatalf("Ошибка чтения ответа: %v", err)
+		return "", fmt.Errorf("ошибка чтения ответа: %w", err)
 	}
 
-	return string(body), err
-}
-
-func getRandomJA3() string {
-	var ja3List = []string{
-		"771,4865-4866-4867-49195-49199-49196-49200-52393-52392-49171-49172-156-157-47-53,11-5-51-65037-23-0-45-65281-27-13-18-35-16-43-10,4588-29-23-24,0",
-		"771,4865-4866-4867-49195-49199-49196-49200-52393-52392-49171-49172-156-157-47-53,16-65037-5-43-10-65281-35-18-51-0-23-45-11-27-13-41,4588-29-23-24,0",
-		"771,4865-4866-4867-49195-49199-49196-49200-52393-52392-49171-49172-156-157-47-53,51-10-43-65281-35-18-5-0-16-65037-13-45-11-23-27,4588-29-23-24,0",
-		"771,4865-4866-4867-49195-49199-49196-49200-52393-52392-49171-49172-156-157-47-53,23-51-16-5-65281-11-35-45-65037-10-13-43-27-0-18-41,4588-29-23-24,0",
-		"771,4865-4866-4867-49195-49199-49196-49200-52393-52392-49171-49172-156-157-47-53,5-45-35-65037-18-11-10-27-51-0-16-23-43-13-65281-41,4588-29-23-24,0",
-		"771,4865-4866-4867-49195-49199-49196-49200-52393-52392-49171-49172-156-157-47-53,18-16-27-23-51-45-43-65037-65281-5-10-11-13-35-0-41,4588-29-23-24,0",
-		"771,4865-4866-4867-49195-49199-49196-49200-52393-52392-49171-49172-156-157-47-53,35-51-45-13-5-0-11-27-23-65037-18-10-16-65281-43-41,4588-29-23-24,0",
-		"771,4865-4866-4867-49195-49199-49196-49200-52393-52392-49171-49172-156-157-47-53,35-45-43-16-23-27-5-51-65281-10-11-13-0-65037-18-41,4588-29-23-24,0",
-		"771,4865-4866-4867-49195-49199-49196-49200-52393-52392-49171-49172-156-157-47-53,23-43-35-45-51-11-18-10-13-65037-16-5-0-65281-27-41,4588-29-23-24,0",
-		"771,4865-4866-4867-49195-49199-49196-49200-52393-52392-49171-49172-156-157-47-53,35-45-10-23-11-5-51-65281-43-0-16-13-18-27-65037-41,4588-29-23-24,0",
-	}
-
-	rand.Seed(time.Now().UnixNano())
-	return ja3List[rand.Intn(len(ja3List))]
+	return string(body), nil
 }
@@ -0,0 +1,247 @@
+/**
+ * package journal
+ *
+ * Run journal. Records every parsed page and keyword of a run and streams results to
+ * disk (JSON Lines) as they arrive, so an interrupted run can be resumed by its id.
+ *
+ * storage/runs/<run-id>/
+ *   journal.jsonl - page / keyword completion records
+ *   results.jsonl - organic items and SERP blocks of completed keywords (line per keyword)
+ *   stats.json    - stats of finished keywords and chunks (merged on resume)
+ */
+
+package journal
+
+import (
+	"encoding/json"
+	"fmt"
+	"parser/services/searchEngine"
+	"parser/services/storage"
+	"sync"
+	"time"
+)
+
+// record statuses
+const (
+	StatusPage    = "page"
+	StatusKeyword = "done"
+)
+
+type Record struct {
+	Engine  string    `json:"engine"`
+	Keyword string    `json:"keyword"`
+	Page    int       `json:"page"`
+	Status  string    `json:"status"`
+	Items   int       `json:"items"`
+	Time    time.Time `json:"time"`
+}
+
+// KeywordResult is a line of results.jsonl
+type KeywordResult struct {
+	Engine  string                   `json:"engine"`
+	Keyword string                   `json:"keyword"`
+	Items   []searchEngine.SERPItem  `json:"items"`
+	Blocks  []searchEngine.SERPBlock `json:"blocks"`
+}
+
+type Journal struct {
+	mu    sync.Mutex
+	id    string
+	dir   string
+	done  map[string]bool
+	stats searchEngine.Stats
+}
+
+// NewRunId returns a run id based on the current time, milliseconds keep runs started
+// within the same second apart
+func NewRunId() string {
+	return time.Now().Format("20060102-150405.000")
+}
+
+// Open opens the run journal. With resume the journal of the existing run is loaded:
+// finished keywords are skipped and stats of the previous run are merged.
+func Open(id string, resume bool) (*Journal, error) {
+	j := &Journal{
+		id:   id,
+		dir:  "runs/" + id,
+		done: map[string]bool{},
+	}
+
+	if !resume {
+		if storage.Exists(j.dir) {
+			return nil, fmt.Errorf("run `%v` already exists", id)
+		}
+
+		return j, nil
+	}
+
+	if !storage.Exists(j.dir) {
+		return nil, fmt.Errorf("run `%v` not found", id)
+	}
+
+	err := storage.ReadJSONLines(j.dir+"/journal.jsonl", func(line []byte) error {
+		var record Record
+
+		if err := json.Unmarshal(line, &record); err != nil {
+			return err
+		}
+
+		if record.Status == StatusKeyword {
+			j.done[key(record.Engine, record.Keyword)] = true
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if storage.Exists(j.dir + "/stats.json") {
+		statsStr, err := storage.ReadFile(j.dir + "/stats.json")
+
+		if err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal([]byte(statsStr), &j.stats); err != nil {
+			return nil, err
+		}
+	}
+
+	return j, nil
+}
+
+func key(engine string, keyword string) string {
+	return engine + "\x00" + keyword
+}
+
+func (j *Journal) Id() string {
+	return j.id
+}
+
+// Done returns the number of finished keywords
+func (j *Journal) Done() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return len(j.done)
+}
+
+func (j *Journal) IsDone(engine string, keyword string) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.done[key(engine, keyword)]
+}
+
+func (j *Journal) PageDone(engine string, keyword string, page int, items int) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return storage.AppendJSONLine(j.dir+"/journal.jsonl", Record{
+		Engine:  engine,
+		Keyword: keyword,
+		Page:    page,
+		Status:  StatusPage,
+		Items:   items,
+		Time:    time.Now(),
+	})
+}
+
+// KeywordDone streams keyword results to disk, the keyword is marked finished
+// only after its results are written. Stats of the keyword (result.Stats) are merged
+// and saved with it, so a killed run keeps the counters of its finished keywords.
+func (j *Journal) KeywordDone(engine string, keyword string, result searchEngine.TResult) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	err := storage.AppendJSONLine(j.dir+"/results.jsonl", KeywordResult{
+		Engine:  engine,
+		Keyword: keyword,
+		Items:   result.Items,
+		Blocks:  result.Blocks,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	err = storage.AppendJSONLine(j.dir+"/journal.jsonl", Record{
+		Engine:  engine,
+		Keyword: keyword,
+		Status:  StatusKeyword,
+		Items:   len(result.Items),
+		Time:    time.Now(),
+	})
+
+	if err != nil {
+		return err
+	}
+
+	j.done[key(engine, keyword)] = true
+	j.stats.Merge(result.Stats)
+
+	return storage.WriteFile(j.dir+"/stats.json", j.stats)
+}
+
+// AddStats merges stats not bound to a finished keyword (failed keywords, process wide
+// counters) and saves the total
+func (j *Journal) AddStats(stats searchEngine.Stats) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.stats.Merge(stats)
+
+	return storage.WriteFile(j.dir+"/stats.json", j.stats)
+}
+
+// Stats returns stats of the run including previous (resumed) runs
+func (j *Journal) Stats() searchEngine.Stats {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.stats
+}
+
+// Results reads streamed results of finished keywords. A keyword written more than once
+// (process killed before the keyword was marked finished) keeps its last result.
+func (j *Journal) Results() ([]searchEngine.SERPItem, []searchEngine.SERPBlock, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	order := []string{}
+	results := map[string]KeywordResult{}
+
+	err := storage.ReadJSONLines(j.dir+"/results.jsonl", func(line []byte) error {
+		var result KeywordResult
+
+		if err := json.Unmarshal(line, &result); err != nil {
+			return err
+		}
+
+		k := key(result.Engine, result.Keyword)
+
+		if !j.done[k] {
+			return nil
+		}
+
+		if _, ok := results[k]; !ok {
+			order = append(order, k)
+		}
+
+		results[k] = result
+
+		return nil
+	})
+
+	items := []searchEngine.SERPItem{}
+	blocks := []searchEngine.SERPBlock{}
+
+	for _, k := range order {
+		items = append(items, results[k].Items...)
+		blocks = append(blocks, results[k].Blocks...)
+	}
+
+	return items, blocks, err
+}
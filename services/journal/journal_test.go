@@ -0,0 +1,200 @@
+package journal
+
+import (
+	"os"
+	"parser/services/searchEngine"
+	"parser/services/storage"
+	"slices"
+	"testing"
+	"time"
+)
+
+// openTemp opens a journal in a storage of a temp dir
+func openTemp(t *testing.T) *Journal {
+	t.Chdir(t.TempDir())
+
+	j, err := Open("test", false)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return j
+}
+
+func result(urls ...string) searchEngine.TResult {
+	r := searchEngine.TResult{}
+
+	for i, url := range urls {
+		r.Items = append(r.Items, searchEngine.SERPItem{Pos: i + 1, URL: url})
+	}
+
+	return r
+}
+
+func urls(items []searchEngine.SERPItem) []string {
+	list := []string{}
+
+	for _, item := range items {
+		list = append(list, item.URL)
+	}
+
+	return list
+}
+
+func keywordDone(t *testing.T, j *Journal, engine string, keyword string, result searchEngine.TResult) {
+	t.Helper()
+
+	if err := j.KeywordDone(engine, keyword, result); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	openTemp(t)
+
+	if _, err := Open("missing", true); err == nil {
+		t.Errorf("expected an error of a missing run")
+	}
+
+	if err := storage.WriteFile("runs/test/stats.json", searchEngine.Stats{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Open("test", false); err == nil {
+		t.Errorf("expected an error of an existing run")
+	}
+}
+
+func TestResume(t *testing.T) {
+	j := openTemp(t)
+
+	if err := j.PageDone("yandex", "first", 0, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	keywordDone(t, j, "yandex", "first", result("https://a.ru/", "https://b.ru/"))
+
+	// killed before the keyword is finished
+	if err := j.PageDone("yandex", "second", 0, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	keywordDone(t, j, "google", "first", result("https://c.ru/"))
+
+	if err := j.AddStats(searchEngine.Stats{TotalPages: 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	resumed, err := Open("test", true)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		engine   string
+		keyword  string
+		expected bool
+	}{
+		{"yandex", "first", true},
+		{"yandex", "second", false},
+		{"google", "first", true},
+		{"google", "second", false},
+	}
+
+	for _, test := range tests {
+		if done := resumed.IsDone(test.engine, test.keyword); done != test.expected {
+			t.Errorf("%v %q: done=%v, want %v", test.engine, test.keyword, done, test.expected)
+		}
+	}
+
+	if resumed.Done() != 2 {
+		t.Errorf("expected 2 finished keywords, got %v", resumed.Done())
+	}
+
+	if err := resumed.AddStats(searchEngine.Stats{TotalPages: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	if pages := resumed.Stats().TotalPages; pages != 5 {
+		t.Errorf("expected stats of both runs (5 pages), got %v", pages)
+	}
+}
+
+func TestKeywordStats(t *testing.T) {
+	j := openTemp(t)
+	keywordResult := result("https://a.ru/")
+	keywordResult.Stats = searchEngine.Stats{TotalPages: 2, LoadingErrors: 1}
+	keywordDone(t, j, "yandex", "first", keywordResult)
+
+	// killed before the chunk is finished: stats of the finished keyword are kept
+	resumed, err := Open("test", true)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stats := resumed.Stats(); stats.TotalPages != 2 || stats.LoadingErrors != 1 {
+		t.Errorf("expected stats of the finished keyword, got %+v", stats)
+	}
+}
+
+func TestResults(t *testing.T) {
+	j := openTemp(t)
+	keywordDone(t, j, "yandex", "first", result("https://a.ru/"))
+	keywordDone(t, j, "yandex", "second", result("https://b.ru/"))
+	// "first" written again by a resumed run: the last result is kept in its place
+	keywordDone(t, j, "yandex", "first", result("https://c.ru/", "https://d.ru/"))
+
+	// results of a keyword that was not marked finished are skipped
+	if err := storage.AppendJSONLine("runs/test/results.jsonl", KeywordResult{
+		Engine:  "yandex",
+		Keyword: "unfinished",
+		Items:   result("https://e.ru/").Items,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// a line broken by a killed process is skipped
+	file, err := os.OpenFile("storage/runs/test/results.jsonl", os.O_APPEND|os.O_WRONLY, 0644)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := file.WriteString(`{"engine":"yandex","keyword":"sec`); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := file.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	resumed, err := Open("test", true)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	items, _, err := resumed.Results()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"https://c.ru/", "https://d.ru/", "https://b.ru/"}
+
+	if actual := urls(items); !slices.Equal(actual, expected) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
+
+func TestNewRunId(t *testing.T) {
+	first := NewRunId()
+	time.Sleep(time.Millisecond * 2)
+
+	if second := NewRunId(); first == second {
+		t.Errorf("runs started apart got the same id %v", first)
+	}
+}
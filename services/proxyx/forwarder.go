@@ -0,0 +1,143 @@
+package proxyx
+
+import (
+	"bufio"
+	netproxy "golang.org/x/net/proxy"
+	"io"
+	"net"
+	"net/http"
+)
+
+// Forwarder is a local HTTP proxy without auth tunneling connections through a SOCKS5 proxy.
+// Chrome can't pass SOCKS5 credentials, so it is pointed to the forwarder instead.
+type Forwarder struct {
+	listener       net.Listener
+	dialer         netproxy.Dialer
+	resolveLocally bool
+}
+
+// StartForwarder listens on a random loopback port until Close
+func StartForwarder(upstream TProxy) (*Forwarder, error) {
+	var auth *netproxy.Auth
+
+	if upstream.User != "" {
+		auth = &netproxy.Auth{
+			User:     upstream.User,
+			Password: upstream.Pass,
+		}
+	}
+
+	dialer, err := netproxy.SOCKS5("tcp", net.JoinHostPort(upstream.Host, upstream.Port), auth, netproxy.Direct)
+
+	if err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+
+	if err != nil {
+		return nil, err
+	}
+
+	forwarder := &Forwarder{
+		listener:       listener,
+		dialer:         dialer,
+		resolveLocally: upstream.GetProtocol() == ProtocolSocks5,
+	}
+
+	go forwarder.serve()
+
+	return forwarder, nil
+}
+
+// Addr returns the proxy url of the forwarder
+func (f *Forwarder) Addr() string {
+	return "http://" + f.listener.Addr().String()
+}
+
+func (f *Forwarder) Close() error {
+	return f.listener.Close()
+}
+
+func (f *Forwarder) serve() {
+	for {
+		conn, err := f.listener.Accept()
+
+		if err != nil {
+			return
+		}
+
+		go f.handle(conn)
+	}
+}
+
+// handle tunnels CONNECT requests and forwards plain http requests (one per connection)
+func (f *Forwarder) handle(conn net.Conn) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	req, err := http.ReadRequest(reader)
+
+	if err != nil {
+		return
+	}
+
+	address := req.Host
+
+	if req.Method != http.MethodConnect {
+		address = req.URL.Host
+
+		if req.URL.Port() == "" {
+			address = net.JoinHostPort(req.URL.Hostname(), "80")
+		}
+	}
+
+	upstream, err := f.dial(address)
+
+	if err != nil {
+		io.WriteString(conn, "HTTP/1.1 502 Bad Gateway\r\nConnection: close\r\n\r\n")
+		return
+	}
+
+	defer upstream.Close()
+
+	if req.Method == http.MethodConnect {
+		io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+	} else {
+		req.Header.Del("Proxy-Connection")
+		req.Header.Del("Proxy-Authorization")
+		req.Close = true
+
+		if err := req.Write(upstream); err != nil {
+			return
+		}
+	}
+
+	go func() {
+		io.Copy(upstream, reader)
+		upstream.Close()
+	}()
+
+	io.Copy(conn, upstream)
+}
+
+// dial resolves the host before dialing for socks5, socks5h leaves it to the proxy
+func (f *Forwarder) dial(address string) (net.Conn, error) {
+	if f.resolveLocally {
+		host, port, err := net.SplitHostPort(address)
+
+		if err != nil {
+			return nil, err
+		}
+
+		ips, err := net.LookupIP(host)
+
+		if err != nil {
+			return nil, err
+		}
+
+		address = net.JoinHostPort(ips[0].String(), port)
+	}
+
+	return f.dialer.Dial("tcp", address)
+}
@@ -0,0 +1,39 @@
+package proxyx
+
+import (
+	"encoding/json"
+	"fmt"
+	"parser/services/config"
+	"parser/services/httpRequest"
+)
+
+// Geo is the exit ip and country of a proxy (config.ProxyGeoUrl, ip-api.com format)
+type Geo struct {
+	Status      string `json:"status"`
+	CountryCode string `json:"countryCode"`
+	Query       string `json:"query"` // exit ip
+}
+
+// GetGeo detects the exit ip and country of the proxy
+func GetGeo(proxy TProxy) (Geo, error) {
+	var geo Geo
+
+	options := map[string]map[string]string{
+		"proxy": {"proxyStr": StructToStr(proxy)},
+	}
+	body, _, err := httpRequest.Get(config.ProxyGeoUrl, options)
+
+	if err != nil {
+		return geo, err
+	}
+
+	if err := json.Unmarshal([]byte(body), &geo); err != nil {
+		return geo, err
+	}
+
+	if geo.Status != "success" {
+		return geo, fmt.Errorf("geo lookup status: %v", geo.Status)
+	}
+
+	return geo, nil
+}
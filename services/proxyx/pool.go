@@ -0,0 +1,254 @@
+package proxyx
+
+import (
+	"parser/services/config"
+	"parser/services/errorx"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ProxyState is the health of a pool proxy for reporting (proxies.json)
+type ProxyState struct {
+	Proxy         string    `json:"proxy"`
+	Score         float64   `json:"score"`
+	Requests      int       `json:"requests"`
+	Successes     int       `json:"successes"`
+	Captchas      int       `json:"captchas"`
+	Bans          int       `json:"bans"`
+	Failures      int       `json:"failures"` // dead proxy / network errors
+	LatencyMs     int64     `json:"latency_ms"`
+	Banned        bool      `json:"banned"`
+	Retired       bool      `json:"retired,omitempty"` // removed from the proxy sources
+	CooldownUntil time.Time `json:"cooldown_until,omitzero"`
+}
+
+type poolEntry struct {
+	proxy    TProxy
+	state    ProxyState
+	latency  time.Duration // moving average of successful requests
+	lastUsed time.Time
+}
+
+// score is the smoothed success rate lowered by latency: a new proxy starts at 0.5,
+// 5 seconds of latency halve the score
+func (e *poolEntry) score() float64 {
+	rate := float64(e.state.Successes+1) / float64(e.state.Requests+2)
+
+	return rate / (1 + e.latency.Seconds()/5)
+}
+
+// ProxyPool hands out the best-scoring proxy that is not banned or cooling down.
+// Health is learned from request outcomes reported by the runner. Safe for concurrent use.
+type ProxyPool struct {
+	mu      sync.Mutex
+	entries map[string]*poolEntry
+	order   []string
+}
+
+func NewProxyPool() *ProxyPool {
+	return &ProxyPool{
+		entries: map[string]*poolEntry{},
+	}
+}
+
+// Add adds new proxies to the pool, known proxies keep their stats
+func (p *ProxyPool) Add(proxies ...TProxy) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, proxy := range proxies {
+		key := StructToStr(proxy)
+
+		if entry, ok := p.entries[key]; ok {
+			entry.state.Retired = false
+			continue
+		}
+
+		p.entries[key] = &poolEntry{
+			proxy: proxy,
+			state: ProxyState{Proxy: key},
+		}
+		p.order = append(p.order, key)
+	}
+}
+
+// Sync makes the pool match a reloaded proxy list: new proxies are added, missing ones
+// are retired (their stats are kept for reporting)
+func (p *ProxyPool) Sync(proxies []TProxy) (int, int) {
+	keep := map[string]bool{}
+
+	for _, proxy := range proxies {
+		keep[StructToStr(proxy)] = true
+	}
+
+	before := p.Len()
+	p.Add(proxies...)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	retired := 0
+
+	for _, key := range p.order {
+		entry := p.entries[key]
+
+		if !keep[key] && !entry.state.Retired {
+			entry.state.Retired = true
+			retired++
+		}
+	}
+
+	return len(p.order) - before, retired
+}
+
+func (p *ProxyPool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return len(p.order)
+}
+
+// Get returns the best-scoring available proxy, least recently used first among equal scores.
+// When every proxy is cooling down Get waits for the first one up to config.ProxyMaxWait,
+// otherwise a KindProxyDead error is returned.
+func (p *ProxyPool) Get() (TProxy, error) {
+	for {
+		proxy, wait, err := p.pick()
+
+		if err != nil || wait == 0 {
+			return proxy, err
+		}
+
+		time.Sleep(wait)
+	}
+}
+
+// Available reports whether the proxy is in the pool and is not banned, retired or cooling down
+func (p *ProxyPool) Available(proxy TProxy) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	entry, ok := p.entries[StructToStr(proxy)]
+
+	if !ok || entry.state.Banned || entry.state.Retired {
+		return false
+	}
+
+	return !entry.state.CooldownUntil.After(time.Now())
+}
+
+func (p *ProxyPool) pick() (TProxy, time.Duration, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var best *poolEntry
+	var nextCooldown time.Time
+	now := time.Now()
+
+	for _, key := range p.order {
+		entry := p.entries[key]
+
+		if entry.state.Banned || entry.state.Retired {
+			continue
+		}
+
+		if entry.state.CooldownUntil.After(now) {
+			if nextCooldown.IsZero() || entry.state.CooldownUntil.Before(nextCooldown) {
+				nextCooldown = entry.state.CooldownUntil
+			}
+
+			continue
+		}
+
+		if best == nil || entry.score() > best.score() ||
+			(entry.score() == best.score() && entry.lastUsed.Before(best.lastUsed)) {
+			best = entry
+		}
+	}
+
+	if best != nil {
+		best.lastUsed = now
+		return best.proxy, 0, nil
+	}
+
+	if nextCooldown.IsZero() {
+		return TProxy{}, 0, errorx.Errorf(errorx.KindProxyDead, "get proxy", "no alive proxy in the pool of %v", len(p.order))
+	}
+
+	wait := nextCooldown.Sub(now)
+
+	if wait > config.ProxyMaxWait {
+		return TProxy{}, 0, errorx.Errorf(errorx.KindProxyDead, "get proxy", "all %v proxies are cooling down (next in %v)", len(p.order), wait.Round(time.Second))
+	}
+
+	return TProxy{}, wait, nil
+}
+
+// Report records the outcome of a request through the proxy: nil error is a success,
+// captcha, ban and dead proxy errors put the proxy into cooldown, too many bans
+// exclude it from the pool. Latency of failed requests is not counted.
+func (p *ProxyPool) Report(proxy TProxy, err error, latency time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	entry, ok := p.entries[StructToStr(proxy)]
+
+	if !ok {
+		return
+	}
+
+	entry.state.Requests++
+
+	if err == nil {
+		entry.state.Successes++
+
+		if latency > 0 {
+			if entry.latency == 0 {
+				entry.latency = latency
+			} else {
+				entry.latency = (entry.latency*4 + latency) / 5
+			}
+		}
+
+		entry.state.LatencyMs = entry.latency.Milliseconds()
+
+		return
+	}
+
+	switch errorx.KindOf(err) {
+	case errorx.KindCaptcha:
+		entry.state.Captchas++
+		entry.state.CooldownUntil = time.Now().Add(config.ProxyCaptchaCooldown)
+	case errorx.KindBan:
+		entry.state.Bans++
+		entry.state.CooldownUntil = time.Now().Add(config.ProxyBanCooldown)
+		entry.state.Banned = entry.state.Bans >= config.ProxyMaxBans
+	case errorx.KindProxyDead:
+		entry.state.Failures++
+		entry.state.CooldownUntil = time.Now().Add(config.ProxyDeadCooldown)
+	default:
+		entry.state.Failures++
+	}
+}
+
+// State returns proxies sorted by score
+func (p *ProxyPool) State() []ProxyState {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	list := []ProxyState{}
+
+	for _, key := range p.order {
+		entry := p.entries[key]
+		state := entry.state
+		state.Score = entry.score()
+		list = append(list, state)
+	}
+
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].Score > list[j].Score
+	})
+
+	return list
+}
@@ -0,0 +1,216 @@
+package proxyx
+
+import (
+	"fmt"
+	"parser/services/config"
+	"parser/services/errorx"
+	"sync"
+	"testing"
+	"time"
+)
+
+func testProxies(t *testing.T, n int) []TProxy {
+	t.Helper()
+
+	proxies := []TProxy{}
+
+	for i := 1; i <= n; i++ {
+		proxy, err := StrToStruct(fmt.Sprintf("http://10.0.0.%v:8080", i))
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		proxies = append(proxies, proxy)
+	}
+
+	return proxies
+}
+
+func stateOf(p *ProxyPool, proxy TProxy) ProxyState {
+	for _, state := range p.State() {
+		if state.Proxy == StructToStr(proxy) {
+			return state
+		}
+	}
+
+	return ProxyState{}
+}
+
+func TestProxyPoolScore(t *testing.T) {
+	proxies := testProxies(t, 3)
+	p := NewProxyPool()
+	p.Add(proxies...)
+
+	if score := stateOf(p, proxies[0]).Score; score != 0.5 {
+		t.Errorf("new proxy score %v, want 0.5", score)
+	}
+
+	// fast and successful > slow and successful > failing
+	for i := 0; i < 5; i++ {
+		p.Report(proxies[0], errorx.Errorf(errorx.KindNetwork, "fetch", "status 502"), 0)
+		p.Report(proxies[1], nil, time.Second*5)
+		p.Report(proxies[2], nil, time.Millisecond*100)
+	}
+
+	states := p.State()
+	expected := []TProxy{proxies[2], proxies[1], proxies[0]}
+
+	for i, proxy := range expected {
+		if states[i].Proxy != StructToStr(proxy) {
+			t.Errorf("state %v: expected %v, got %v (score %v)", i, StructToStr(proxy), states[i].Proxy, states[i].Score)
+		}
+	}
+
+	if proxy, err := p.Get(); err != nil || StructToStr(proxy) != StructToStr(proxies[2]) {
+		t.Errorf("expected the best scoring proxy, got %v (%v)", StructToStr(proxy), err)
+	}
+}
+
+func TestProxyPoolCooldown(t *testing.T) {
+	tests := []struct {
+		kind     errorx.Kind
+		cooldown time.Duration
+	}{
+		{errorx.KindCaptcha, config.ProxyCaptchaCooldown},
+		{errorx.KindBan, config.ProxyBanCooldown},
+		{errorx.KindProxyDead, config.ProxyDeadCooldown},
+		{errorx.KindNetwork, 0},
+	}
+
+	for _, test := range tests {
+		proxies := testProxies(t, 2)
+		p := NewProxyPool()
+		p.Add(proxies...)
+		p.Report(proxies[0], errorx.Errorf(test.kind, "fetch", "test"), 0)
+
+		state := stateOf(p, proxies[0])
+		until := time.Until(state.CooldownUntil)
+
+		if test.cooldown == 0 {
+			if !state.CooldownUntil.IsZero() {
+				t.Errorf("%v: unexpected cooldown till %v", test.kind, state.CooldownUntil)
+			}
+
+			continue
+		}
+
+		if until <= test.cooldown-time.Second || until > test.cooldown {
+			t.Errorf("%v: cooldown %v, want %v", test.kind, until, test.cooldown)
+		}
+
+		// the cooling down proxy is skipped
+		for i := 0; i < 3; i++ {
+			if proxy, err := p.Get(); err != nil || StructToStr(proxy) != StructToStr(proxies[1]) {
+				t.Errorf("%v: expected the other proxy, got %v (%v)", test.kind, StructToStr(proxy), err)
+			}
+		}
+	}
+}
+
+func TestProxyPoolAllCoolingDown(t *testing.T) {
+	proxies := testProxies(t, 1)
+	p := NewProxyPool()
+	p.Add(proxies...)
+	p.Report(proxies[0], errorx.Errorf(errorx.KindBan, "fetch", "status 403"), 0)
+
+	if config.ProxyBanCooldown <= config.ProxyMaxWait {
+		t.Skip("ban cooldown fits config.ProxyMaxWait")
+	}
+
+	if _, err := p.Get(); !errorx.Is(err, errorx.KindProxyDead) {
+		t.Errorf("expected a proxy_dead error, got %v", err)
+	}
+}
+
+func TestProxyPoolMaxBans(t *testing.T) {
+	proxies := testProxies(t, 1)
+	p := NewProxyPool()
+	p.Add(proxies...)
+
+	for i := 1; i <= config.ProxyMaxBans; i++ {
+		p.Report(proxies[0], errorx.Errorf(errorx.KindBan, "fetch", "status 429"), 0)
+
+		if banned := stateOf(p, proxies[0]).Banned; banned != (i >= config.ProxyMaxBans) {
+			t.Errorf("ban %v: banned=%v", i, banned)
+		}
+	}
+
+	if _, err := p.Get(); !errorx.Is(err, errorx.KindProxyDead) {
+		t.Errorf("expected no alive proxy, got %v", err)
+	}
+}
+
+func TestProxyPoolSyncRetires(t *testing.T) {
+	proxies := testProxies(t, 2)
+	p := NewProxyPool()
+	p.Add(proxies...)
+
+	if added, retired := p.Sync(proxies[1:]); added != 0 || retired != 1 {
+		t.Fatalf("expected 1 retired proxy, got %v added, %v retired", added, retired)
+	}
+
+	for i := 0; i < 3; i++ {
+		if proxy, _ := p.Get(); StructToStr(proxy) != StructToStr(proxies[1]) {
+			t.Errorf("retired proxy %v returned", StructToStr(proxy))
+		}
+	}
+
+	// back in the list: active again with its stats
+	if added, _ := p.Sync(proxies); added != 0 || stateOf(p, proxies[0]).Retired {
+		t.Errorf("expected the retired proxy to be active again")
+	}
+}
+
+func TestProxyPoolConcurrent(t *testing.T) {
+	proxies := testProxies(t, 5)
+	p := NewProxyPool()
+	p.Add(proxies...)
+
+	var wg sync.WaitGroup
+
+	for worker := 0; worker < 10; worker++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := 0; i < 100; i++ {
+				proxy, err := p.Get()
+
+				if err != nil {
+					return
+				}
+
+				p.Report(proxy, nil, time.Millisecond*time.Duration(i))
+				p.State()
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	requests := 0
+
+	for _, state := range p.State() {
+		requests += state.Requests
+	}
+
+	if requests != 1000 {
+		t.Errorf("expected 1000 reported requests, got %v", requests)
+	}
+}
+
+func TestProxyPoolAvailable(t *testing.T) {
+	proxies := testProxies(t, 4)
+	p := NewProxyPool()
+	p.Add(proxies[:3]...)
+	p.Report(proxies[1], errorx.Errorf(errorx.KindCaptcha, "fetch", "showcaptcha"), 0)
+	p.Sync(proxies[:2])
+
+	for i, expected := range []bool{true, false, false, false} {
+		if available := p.Available(proxies[i]); available != expected {
+			t.Errorf("proxy %v: available=%v, want %v", i, available, expected)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package proxyx
+
+// proxy check verdicts (see cmd/check-proxy.go)
+const (
+	VerdictOk      = "ok"
+	VerdictCaptcha = "captcha" // the search engine serves captcha to the proxy
+	VerdictBanned  = "banned"  // 403 / 429 from the search engine
+	VerdictDead    = "dead"    // proxy does not respond
+)
+
+// CheckReport is the audit result of a proxy. Reports saved as JSON or CSV are read
+// back by FileSource, only proxies with the ok verdict are used.
+type CheckReport struct {
+	Proxy        string `json:"proxy"`
+	Verdict      string `json:"verdict"`
+	Target       string `json:"target"`
+	ExitIP       string `json:"exit_ip"`
+	Country      string `json:"country"`
+	Requests     int    `json:"requests"`
+	Successes    int    `json:"successes"`
+	Captchas     int    `json:"captchas"`
+	LatencyP50Ms int64  `json:"latency_p50_ms"`
+	LatencyP90Ms int64  `json:"latency_p90_ms"`
+	Error        string `json:"error,omitempty"`
+}
+
+// CheckReportHeader is the CSV header of reports
+var CheckReportHeader = []string{"proxy", "verdict", "target", "exit_ip", "country", "requests", "successes", "captchas", "latency_p50_ms", "latency_p90_ms", "error"}
@@ -0,0 +1,296 @@
+package proxyx
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"parser/services/config"
+	"parser/services/errorx"
+	"parser/services/httpRequest"
+	"parser/services/storage"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ProxySource loads a proxy list. Lines are `[scheme://][user:pass@]host:port`.
+type ProxySource interface {
+	Name() string
+	Load() ([]TProxy, error)
+}
+
+// FileSource reads proxies from a storage file: a text list or check reports
+// (.json / .csv written by cmd/check-proxy.go)
+type FileSource struct {
+	Path string
+}
+
+func (s FileSource) Name() string {
+	return "file " + s.Path
+}
+
+func (s FileSource) Load() ([]TProxy, error) {
+	data, err := storage.ReadFile(s.Path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(filepath.Ext(s.Path)) {
+	case ".json":
+		reports := []CheckReport{}
+
+		if err := json.Unmarshal([]byte(data), &reports); err != nil {
+			return nil, fmt.Errorf("%v: %w", s.Path, err)
+		}
+
+		return reportsToList(reports), nil
+	case ".csv":
+		reports, err := parseCsvReports(data)
+
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", s.Path, err)
+		}
+
+		return reportsToList(reports), nil
+	}
+
+	return ParseList(data), nil
+}
+
+func parseCsvReports(data string) ([]CheckReport, error) {
+	rows, err := csv.NewReader(strings.NewReader(data)).ReadAll()
+
+	if err != nil || len(rows) == 0 {
+		return nil, err
+	}
+
+	columns := map[string]int{}
+
+	for i, name := range rows[0] {
+		columns[name] = i
+	}
+
+	proxyColumn, ok := columns["proxy"]
+
+	if !ok {
+		return nil, fmt.Errorf("no `proxy` column")
+	}
+
+	verdictColumn, hasVerdict := columns["verdict"]
+	reports := []CheckReport{}
+
+	for _, row := range rows[1:] {
+		report := CheckReport{Proxy: row[proxyColumn]}
+
+		if hasVerdict {
+			report.Verdict = row[verdictColumn]
+		}
+
+		reports = append(reports, report)
+	}
+
+	return reports, nil
+}
+
+// reportsToList keeps proxies with the ok verdict (or without a verdict)
+func reportsToList(reports []CheckReport) []TProxy {
+	lines := []string{}
+
+	for _, report := range reports {
+		if report.Verdict == "" || report.Verdict == VerdictOk {
+			lines = append(lines, report.Proxy)
+		}
+	}
+
+	return ParseList(strings.Join(lines, "\n"))
+}
+
+// HttpSource loads proxies from a provider endpoint returning the list as text
+type HttpSource struct {
+	Url string
+}
+
+func (s HttpSource) Name() string {
+	return "provider"
+}
+
+func (s HttpSource) Load() ([]TProxy, error) {
+	if s.Url == "" {
+		return nil, fmt.Errorf("PROXY_PROVIDER_URL is not set")
+	}
+
+	options := map[string]map[string]string{}
+	response, resp, err := httpRequest.Get(s.Url, options)
+
+	if err != nil {
+		return nil, errorx.New(errorx.KindNetwork, "load proxy list", err)
+	}
+
+	if resp.StatusCode >= 400 {
+		return nil, errorx.Errorf(errorx.KindNetwork, "load proxy list", "status %v", resp.StatusCode)
+	}
+
+	return ParseList(response), nil
+}
+
+// ListSource is an inline list separated by commas or new lines
+type ListSource struct {
+	List string
+}
+
+func (s ListSource) Name() string {
+	return "list"
+}
+
+func (s ListSource) Load() ([]TProxy, error) {
+	return ParseList(strings.ReplaceAll(s.List, ",", "\n")), nil
+}
+
+// CommandSource runs a shell command printing the proxy list to stdout
+type CommandSource struct {
+	Command string
+}
+
+func (s CommandSource) Name() string {
+	return "command"
+}
+
+func (s CommandSource) Load() ([]TProxy, error) {
+	output, err := exec.Command("sh", "-c", s.Command).Output()
+
+	if err != nil {
+		return nil, fmt.Errorf("proxy command: %w", err)
+	}
+
+	return ParseList(string(output)), nil
+}
+
+// ParseList parses proxy lines skipping empty lines, # comments and malformed proxies
+func ParseList(text string) []TProxy {
+	proxies := []TProxy{}
+
+	for _, proxyStr := range strings.Split(text, "\n") {
+		proxyStr = strings.TrimSpace(proxyStr)
+
+		if proxyStr == "" || strings.HasPrefix(proxyStr, "#") {
+			continue
+		}
+
+		proxy, err := StrToStruct(proxyStr)
+
+		if err != nil {
+			log.Printf("[WARN] %v", err)
+			continue
+		}
+
+		proxies = append(proxies, proxy)
+	}
+
+	return proxies
+}
+
+// GetSources returns sources by comma separated names: provider, file, list, command
+func GetSources(names string) ([]ProxySource, error) {
+	sources := []ProxySource{}
+
+	for _, name := range strings.Split(names, ",") {
+		switch strings.TrimSpace(name) {
+		case "provider":
+			sources = append(sources, HttpSource{Url: os.Getenv("PROXY_PROVIDER_URL")})
+		case "file":
+			sources = append(sources, FileSource{Path: config.ProxyFile})
+		case "list":
+			sources = append(sources, ListSource{List: config.ProxyList})
+		case "command":
+			sources = append(sources, CommandSource{Command: config.ProxyCommand})
+		default:
+			return nil, fmt.Errorf("unknown proxy source `%v` (available: provider, file, list, command)", name)
+		}
+	}
+
+	return sources, nil
+}
+
+// LoadSources merges proxies of all sources without duplicates. A failed source is
+// skipped, the error is returned only when no source could be loaded.
+func LoadSources(sources []ProxySource) ([]TProxy, error) {
+	return newSourceCache(sources).load()
+}
+
+// sourceCache keeps the last loaded list of every source, so a source failing to reload
+// (provider timeout ...) keeps supplying its previous proxies instead of retiring them
+type sourceCache struct {
+	sources []ProxySource
+	lists   map[int][]TProxy // last good list by source index
+}
+
+func newSourceCache(sources []ProxySource) *sourceCache {
+	return &sourceCache{
+		sources: sources,
+		lists:   map[int][]TProxy{},
+	}
+}
+
+// load reloads the sources and merges their lists without duplicates, a failed source
+// contributes its last good list. The error is returned only when no source has a list.
+func (c *sourceCache) load() ([]TProxy, error) {
+	proxies := []TProxy{}
+	seen := map[string]bool{}
+	var lastErr error
+	loaded := 0
+
+	for i, source := range c.sources {
+		list, err := source.Load()
+
+		if err != nil {
+			lastErr = err
+			previous, ok := c.lists[i]
+
+			if !ok {
+				log.Printf("[WARN] Proxy source %v: %v", source.Name(), err)
+				continue
+			}
+
+			log.Printf("[WARN] Proxy source %v: %v, keep %v proxies of the last load", source.Name(), err, len(previous))
+			list = previous
+		}
+
+		c.lists[i] = list
+		loaded++
+
+		for _, proxy := range list {
+			key := StructToStr(proxy)
+
+			if !seen[key] {
+				seen[key] = true
+				proxies = append(proxies, proxy)
+			}
+		}
+	}
+
+	if loaded == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+
+	return proxies, nil
+}
+
+// refresh reloads the sources while the run is in progress. Proxies that disappeared
+// from all sources are retired, a failed source keeps its proxies of the last load.
+func refresh(sources *sourceCache, interval time.Duration) {
+	for range time.Tick(interval) {
+		proxies, err := sources.load()
+
+		if err != nil || len(proxies) == 0 {
+			log.Printf("[WARN] Proxy list refresh failed: %v", err)
+			continue
+		}
+
+		added, retired := pool.Sync(proxies)
+		log.Printf("[INFO] Proxy list refreshed: %v proxies, %v added, %v retired", len(proxies), added, retired)
+	}
+}
@@ -0,0 +1,76 @@
+package proxyx
+
+import (
+	"fmt"
+	"testing"
+)
+
+// stubSource returns its list or fails while err is set
+type stubSource struct {
+	name string
+	list string
+	err  error
+}
+
+func (s *stubSource) Name() string {
+	return s.name
+}
+
+func (s *stubSource) Load() ([]TProxy, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	return ParseList(s.list), nil
+}
+
+func TestSourceCacheKeepsListOfFailedSource(t *testing.T) {
+	provider := &stubSource{name: "provider", list: "1.1.1.1:8080\n2.2.2.2:8080"}
+	file := &stubSource{name: "file", list: "3.3.3.3:8080\n1.1.1.1:8080"}
+	cache := newSourceCache([]ProxySource{provider, file})
+
+	proxies, err := cache.load()
+
+	if err != nil || len(proxies) != 3 {
+		t.Fatalf("expected 3 proxies, got %v (%v)", len(proxies), err)
+	}
+
+	testPool := NewProxyPool()
+	testPool.Add(proxies...)
+
+	// provider timeout: its proxies stay in the pool
+	provider.err = fmt.Errorf("timeout")
+	proxies, err = cache.load()
+
+	if err != nil || len(proxies) != 3 {
+		t.Fatalf("expected 3 proxies after a failed reload, got %v (%v)", len(proxies), err)
+	}
+
+	if added, retired := testPool.Sync(proxies); added != 0 || retired != 0 {
+		t.Errorf("failed reload changed the pool: %v added, %v retired", added, retired)
+	}
+
+	// provider is back without 2.2.2.2
+	provider.err = nil
+	provider.list = "1.1.1.1:8080"
+	proxies, _ = cache.load()
+
+	if added, retired := testPool.Sync(proxies); added != 0 || retired != 1 {
+		t.Errorf("expected 1 retired proxy, got %v added, %v retired", added, retired)
+	}
+}
+
+func TestLoadSourcesFailures(t *testing.T) {
+	failed := &stubSource{name: "provider", err: fmt.Errorf("timeout")}
+	list := &stubSource{name: "list", list: "1.1.1.1:8080"}
+
+	proxies, err := LoadSources([]ProxySource{failed, list})
+
+	if err != nil || len(proxies) != 1 {
+		t.Errorf("failed source must be skipped, got %v proxies (%v)", len(proxies), err)
+	}
+
+	if _, err := LoadSources([]ProxySource{failed}); err == nil {
+		t.Errorf("expected an error when no source is loaded")
+	}
+}
@@ -0,0 +1,93 @@
+/**
+ * package rucaptcha
+ *
+ * RuCaptcha / 2Captcha solver (API v2). Keys are taken from RUCAPTCHA_API_KEY and
+ * TWOCAPTCHA_API_KEY.
+ */
+
+package rucaptcha
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"parser/services/captcha"
+	"parser/services/config"
+	"parser/services/geometry"
+)
+
+type Solver struct {
+	name   string
+	url    string
+	keyEnv string
+}
+
+func init() {
+	captcha.Register(Solver{name: "rucaptcha", url: config.RuCaptchaUrl, keyEnv: "RUCAPTCHA_API_KEY"})
+	captcha.Register(Solver{name: "2captcha", url: config.TwoCaptchaUrl, keyEnv: "TWOCAPTCHA_API_KEY"})
+}
+
+// api reads the key on every call: env is loaded after package init
+func (s Solver) api() captcha.TaskApi {
+	return captcha.TaskApi{
+		Url: s.url,
+		Key: os.Getenv(s.keyEnv),
+	}
+}
+
+func (s Solver) Name() string {
+	return s.name
+}
+
+func (s Solver) Submit(task captcha.Task) (string, error) {
+	switch task.Type {
+	case captcha.TaskSilhouette, captcha.TaskSlider:
+		request := map[string]any{
+			"type":    "CoordinatesTask",
+			"body":    base64.StdEncoding.EncodeToString(task.Image),
+			"comment": task.Comment,
+		}
+
+		if len(task.Instruction) > 0 {
+			request["imgInstructions"] = base64.StdEncoding.EncodeToString(task.Instruction)
+		}
+
+		return s.api().CreateTask(request)
+	case captcha.TaskText:
+		return s.api().CreateTask(map[string]any{
+			"type":    "ImageToTextTask",
+			"body":    base64.StdEncoding.EncodeToString(task.Image),
+			"comment": task.Comment,
+		})
+	}
+
+	return "", fmt.Errorf("%v: unsupported task type `%v`", s.name, task.Type)
+}
+
+func (s Solver) Poll(taskId string) (captcha.Solution, error) {
+	var solution struct {
+		Coordinates []struct {
+			X float64 `json:"x"`
+			Y float64 `json:"y"`
+		} `json:"coordinates"`
+		Text string `json:"text"`
+	}
+
+	if err := s.api().GetTaskResult(taskId, &solution); err != nil {
+		return captcha.Solution{}, err
+	}
+
+	result := captcha.Solution{
+		Text: solution.Text,
+	}
+
+	for _, point := range solution.Coordinates {
+		result.Points = append(result.Points, geometry.Point{X: point.X, Y: point.Y})
+	}
+
+	return result, nil
+}
+
+func (s Solver) ReportBad(taskId string) error {
+	return s.api().Report("reportIncorrect", taskId)
+}
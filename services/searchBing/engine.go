@@ -0,0 +1,39 @@
+package searchBing
+
+import (
+	"parser/services/proxyx"
+	"parser/services/searchEngine"
+)
+
+// Engine is the bing.com html search engine
+type Engine struct{}
+
+func init() {
+	searchEngine.Register(Engine{})
+}
+
+func (Engine) Name() string {
+	return "bing"
+}
+
+func (Engine) GetSearchPageUrl(text string, lr string, page int) string {
+	return GetSearchPageUrl(text, lr, page)
+}
+
+func (Engine) Fetch(pageUrl string, session *searchEngine.Session, proxy *proxyx.TProxy) (searchEngine.TResponse, error) {
+	return searchEngine.Fetch(pageUrl, GetHeaders(), session, proxy)
+}
+
+func (Engine) IsBlocked(resp searchEngine.TResponse) bool {
+	return IsBlocked(resp.FinalUrl, resp.Html)
+}
+
+func (Engine) ParsePage(html string) searchEngine.ParsedPage {
+	return ParsePage(html)
+}
+
+func (Engine) GenerateSession(text string, lr string, proxy *proxyx.TProxy, oldSession *searchEngine.Session) (searchEngine.Session, int, error) {
+	session, err := searchEngine.GenerateHttpSession("https://www.bing.com/", GetHeaders(), proxy)
+
+	return session, 0, err
+}
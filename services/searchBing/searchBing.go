@@ -0,0 +1,155 @@
+package searchBing
+
+import (
+	"encoding/base64"
+	"github.com/PuerkitoBio/goquery"
+	"net/url"
+	"parser/services/config"
+	"parser/services/searchEngine"
+	"strconv"
+	"strings"
+)
+
+const pageSize = 10
+
+// blockMarkers are page fragments of bing captcha / challenge pages
+var blockMarkers = []string{
+	"/turing/captcha",
+	"b_captcha",
+	"id=\"challenge\"",
+}
+
+// GetSearchPageUrl формирует URL поиска Bing, страница задаётся параметром first (с единицы)
+func GetSearchPageUrl(text string, lr string, page int) string {
+	pageUrl, _ := url.Parse("https://www.bing.com/search")
+	params := url.Values{}
+	params.Add("q", text)
+	params.Add("cc", config.BingCc)
+	params.Add("setlang", config.BingSetlang)
+	params.Add("count", strconv.Itoa(pageSize))
+
+	if page > 0 {
+		params.Add("first", strconv.Itoa(page*pageSize+1))
+	}
+
+	pageUrl.RawQuery = params.Encode()
+
+	return pageUrl.String()
+}
+
+func GetHeaders() map[string]string {
+	return map[string]string{
+		"Accept":                    "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8",
+		"Accept-Encoding":           "gzip, deflate, br",
+		"Accept-Language":           "ru-RU,ru;q=0.9,en-US;q=0.8,en;q=0.7",
+		"Sec-Fetch-Dest":            "document",
+		"Sec-Fetch-Mode":            "navigate",
+		"Sec-Fetch-Site":            "same-origin",
+		"Upgrade-Insecure-Requests": "1",
+		"Referer":                   "https://www.bing.com/",
+	}
+}
+
+func IsBlocked(finalUrl string, html string) bool {
+	if strings.Contains(finalUrl, "/turing/") || strings.Contains(finalUrl, "/challenge") {
+		return true
+	}
+
+	for _, marker := range blockMarkers {
+		if strings.Contains(html, marker) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func ParsePage(html string) searchEngine.ParsedPage {
+	doc, _ := goquery.NewDocumentFromReader(strings.NewReader(html))
+
+	result := searchEngine.ParsedPage{
+		Items:  []searchEngine.SERPItem{},
+		Blocks: []searchEngine.SERPBlock{},
+	}
+	// pagination, answers and other list items are not SERP blocks and take no position
+	pos := 0
+	doc.Find("#b_results > li").Each(func(i int, node *goquery.Selection) {
+		aNode := node.Find("h2 a").First()
+		textEl := node.Find(".b_caption p, p.b_lineclamp2, p.b_algoSlug").First()
+		linkUrl, _ := aNode.Attr("href")
+		u, _ := url.Parse(cleanUrl(linkUrl))
+
+		switch {
+		case node.HasClass("b_ad"):
+			pos++
+			placement := "top"
+
+			if node.HasClass("b_adBottom") || len(result.Items) > 0 {
+				placement = "bottom"
+			}
+
+			result.Blocks = append(result.Blocks, searchEngine.SERPBlock{
+				Type:      searchEngine.BlockAd,
+				Pos:       pos,
+				Placement: placement,
+				URL:       u.String(),
+				Domain:    u.Hostname(),
+				Title:     aNode.Text(),
+				Text:      textEl.Text(),
+			})
+		case node.HasClass("b_algo"):
+			pos++
+			result.Items = append(result.Items, searchEngine.SERPItem{
+				Pos:    len(result.Items) + 1,
+				AbsPos: pos,
+				URL:    u.String(),
+				Domain: u.Hostname(),
+				Title:  aNode.Text(),
+				Text:   textEl.Text(),
+			})
+		}
+	})
+
+	related := searchEngine.SERPBlock{
+		Type: searchEngine.BlockRelated,
+		Pos:  pos + 1,
+	}
+
+	doc.Find(".b_rs li a").Each(func(i int, aNode *goquery.Selection) {
+		related.Items = append(related.Items, searchEngine.SERPItem{
+			Pos:   i + 1,
+			Title: strings.TrimSpace(aNode.Text()),
+		})
+	})
+
+	result.Length = pos
+
+	if len(related.Items) > 0 {
+		result.Blocks = append(result.Blocks, related)
+		result.Length++
+	}
+
+	return result
+}
+
+// cleanUrl unwraps bing.com/ck/a redirects (target is base64url in `u` after the "a1" prefix)
+func cleanUrl(href string) string {
+	if !strings.Contains(href, "bing.com/ck/a") {
+		return href
+	}
+
+	u, err := url.Parse(href)
+
+	if err != nil {
+		return href
+	}
+
+	encoded := strings.TrimPrefix(u.Query().Get("u"), "a1")
+	decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(encoded, "="))
+
+	if err != nil {
+		return href
+	}
+
+	return string(decoded)
+}
@@ -0,0 +1,39 @@
+package searchDuckDuckGo
+
+import (
+	"parser/services/proxyx"
+	"parser/services/searchEngine"
+)
+
+// Engine is the html.duckduckgo.com search engine
+type Engine struct{}
+
+func init() {
+	searchEngine.Register(Engine{})
+}
+
+func (Engine) Name() string {
+	return "duckduckgo"
+}
+
+func (Engine) GetSearchPageUrl(text string, lr string, page int) string {
+	return GetSearchPageUrl(text, lr, page)
+}
+
+func (Engine) Fetch(pageUrl string, session *searchEngine.Session, proxy *proxyx.TProxy) (searchEngine.TResponse, error) {
+	return searchEngine.Fetch(pageUrl, GetHeaders(), session, proxy)
+}
+
+func (Engine) IsBlocked(resp searchEngine.TResponse) bool {
+	return IsBlocked(resp.Status, resp.Html)
+}
+
+func (Engine) ParsePage(html string) searchEngine.ParsedPage {
+	return ParsePage(html)
+}
+
+func (Engine) GenerateSession(text string, lr string, proxy *proxyx.TProxy, oldSession *searchEngine.Session) (searchEngine.Session, int, error) {
+	session, err := searchEngine.GenerateHttpSession("https://html.duckduckgo.com/html/", GetHeaders(), proxy)
+
+	return session, 0, err
+}
@@ -0,0 +1,95 @@
+package searchDuckDuckGo
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"parser/services/searchEngine"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// go test ./services/searchDuckDuckGo -update rewrites golden files after an intended markup change
+var update = flag.Bool("update", false, "update golden files")
+
+type golden struct {
+	Blocked bool                    `json:"blocked"`
+	Page    searchEngine.ParsedPage `json:"page"`
+}
+
+func readFixture(t *testing.T, name string) string {
+	t.Helper()
+
+	data, err := os.ReadFile("testdata/" + name)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(data)
+}
+
+func TestParsePageGolden(t *testing.T) {
+	fixtures, err := filepath.Glob("testdata/*.html")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, fixture := range fixtures {
+		name := strings.TrimSuffix(filepath.Base(fixture), ".html")
+
+		t.Run(name, func(t *testing.T) {
+			html := readFixture(t, filepath.Base(fixture))
+			actual, err := json.MarshalIndent(golden{
+				Blocked: IsBlocked(200, html),
+				Page:    ParsePage(html),
+			}, "", "  ")
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			goldenPath := "testdata/" + name + ".golden.json"
+
+			if *update {
+				if err := os.WriteFile(goldenPath, append(actual, '\n'), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			expected, err := os.ReadFile(goldenPath)
+
+			if err != nil {
+				t.Fatalf("no golden file (run with -update): %v", err)
+			}
+
+			if !bytes.Equal(bytes.TrimSpace(expected), bytes.TrimSpace(actual)) {
+				t.Errorf("parsed page differs from %v\nexpected:\n%s\nactual:\n%s", goldenPath, expected, actual)
+			}
+		})
+	}
+}
+
+func TestIsBlocked(t *testing.T) {
+	cases := []struct {
+		name     string
+		status   int
+		fixture  string
+		expected bool
+	}{
+		{"anomaly status", 202, "organic.html", true},
+		{"anomaly page", 200, "anomaly.html", true},
+		{"organic", 200, "organic.html", false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if actual := IsBlocked(c.status, readFixture(t, c.fixture)); actual != c.expected {
+				t.Errorf("expected %v, got %v", c.expected, actual)
+			}
+		})
+	}
+}
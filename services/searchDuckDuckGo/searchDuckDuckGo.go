@@ -0,0 +1,128 @@
+package searchDuckDuckGo
+
+import (
+	"github.com/PuerkitoBio/goquery"
+	"net/url"
+	"parser/services/config"
+	"parser/services/searchEngine"
+	"strconv"
+	"strings"
+)
+
+// html endpoint returns up to 30 results per page
+const pageSize = 30
+
+// blockMarkers are page fragments of the duckduckgo anomaly (bot) page
+var blockMarkers = []string{
+	"anomaly-modal",
+	"challenge-form",
+	"bots use DuckDuckGo too",
+}
+
+// GetSearchPageUrl формирует URL html-версии DuckDuckGo, регион задаётся параметром kl
+func GetSearchPageUrl(text string, lr string, page int) string {
+	pageUrl, _ := url.Parse("https://html.duckduckgo.com/html/")
+	params := url.Values{}
+	params.Add("q", text)
+	params.Add("kl", config.DuckDuckGoKl)
+
+	if page > 0 {
+		params.Add("s", strconv.Itoa(page*pageSize))
+		params.Add("dc", strconv.Itoa(page*pageSize+1))
+	}
+
+	pageUrl.RawQuery = params.Encode()
+
+	return pageUrl.String()
+}
+
+func GetHeaders() map[string]string {
+	return map[string]string{
+		"Accept":                    "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8",
+		"Accept-Encoding":           "gzip, deflate, br",
+		"Accept-Language":           "ru-RU,ru;q=0.9,en-US;q=0.8,en;q=0.7",
+		"Upgrade-Insecure-Requests": "1",
+		"Referer":                   "https://html.duckduckgo.com/",
+	}
+}
+
+// IsBlocked detects the anomaly page (duckduckgo answers it with status 202)
+func IsBlocked(status int, html string) bool {
+	if status == 202 {
+		return true
+	}
+
+	for _, marker := range blockMarkers {
+		if strings.Contains(html, marker) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func ParsePage(html string) searchEngine.ParsedPage {
+	doc, _ := goquery.NewDocumentFromReader(strings.NewReader(html))
+
+	result := searchEngine.ParsedPage{
+		Items:  []searchEngine.SERPItem{},
+		Blocks: []searchEngine.SERPBlock{},
+	}
+	nodes := doc.Find("#links .result")
+	nodes.Each(func(i int, node *goquery.Selection) {
+		aNode := node.Find("a.result__a").First()
+		textEl := node.Find(".result__snippet").First()
+		linkUrl, _ := aNode.Attr("href")
+		u, _ := url.Parse(cleanUrl(linkUrl))
+
+		// ads are shown above organic results
+		if node.HasClass("result--ad") {
+			result.Blocks = append(result.Blocks, searchEngine.SERPBlock{
+				Type:      searchEngine.BlockAd,
+				Pos:       i + 1,
+				Placement: "top",
+				URL:       u.String(),
+				Domain:    u.Hostname(),
+				Title:     strings.TrimSpace(aNode.Text()),
+				Text:      strings.TrimSpace(textEl.Text()),
+			})
+			return
+		}
+
+		result.Items = append(result.Items, searchEngine.SERPItem{
+			Pos:    len(result.Items) + 1,
+			AbsPos: i + 1,
+			URL:    u.String(),
+			Domain: u.Hostname(),
+			Title:  strings.TrimSpace(aNode.Text()),
+			Text:   strings.TrimSpace(textEl.Text()),
+		})
+	})
+
+	result.Length = nodes.Length()
+
+	return result
+}
+
+// cleanUrl unwraps //duckduckgo.com/l/?uddg= redirects
+func cleanUrl(href string) string {
+	if !strings.Contains(href, "duckduckgo.com/l/") {
+		return href
+	}
+
+	if strings.HasPrefix(href, "//") {
+		href = "https:" + href
+	}
+
+	u, err := url.Parse(href)
+
+	if err != nil {
+		return href
+	}
+
+	if target := u.Query().Get("uddg"); target != "" {
+		return target
+	}
+
+	return href
+}
@@ -0,0 +1,93 @@
+package searchEngine
+
+import (
+	"github.com/chromedp/cdproto/network"
+	"parser/services/httpRequest"
+	"parser/services/proxyx"
+	"parser/services/useragent"
+	"strings"
+)
+
+// Fetch loads the page with CycleTLS using given headers, session cookies, fingerprint and proxy
+func Fetch(pageUrl string, headers map[string]string, session *Session, proxy *proxyx.TProxy) (TResponse, error) {
+	options := map[string]map[string]string{
+		"headers": headers,
+	}
+
+	if session != nil {
+		if len(session.Cookie) > 0 {
+			headers["Cookie"] = CookieToString(session.Cookie)
+		}
+
+		options["fingerprint"] = fingerprintOptions(headers, session.Fingerprint)
+	}
+
+	if proxy != nil {
+		options["proxy"] = map[string]string{
+			"proxyStr": proxyx.StructToStr(*proxy),
+		}
+	}
+
+	html, resp, err := httpRequest.GetCycleTls(pageUrl, &options)
+
+	return TResponse{
+		Html:     html,
+		Status:   resp.Status,
+		FinalUrl: resp.FinalUrl,
+	}, err
+}
+
+// GenerateHttpSession collects cookies of the page without a browser.
+// Used by engines that do not need a trusted (captcha passed) session.
+func GenerateHttpSession(pageUrl string, headers map[string]string, proxy *proxyx.TProxy) (Session, error) {
+	session := NewSession(proxy, nil)
+	options := map[string]map[string]string{
+		"headers":     headers,
+		"fingerprint": fingerprintOptions(headers, session.Fingerprint),
+	}
+
+	if proxy != nil {
+		options["proxy"] = map[string]string{
+			"proxyStr": proxyx.StructToStr(*proxy),
+		}
+	}
+
+	_, resp, err := httpRequest.GetCycleTls(pageUrl, &options)
+
+	if err != nil {
+		return Session{}, err
+	}
+
+	for _, c := range resp.Cookies {
+		cookie := &network.Cookie{
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   c.Domain,
+			Path:     c.Path,
+			Secure:   c.Secure,
+			HTTPOnly: c.HttpOnly,
+		}
+
+		if !c.Expires.IsZero() {
+			cookie.Expires = float64(c.Expires.Unix())
+		}
+
+		session.Cookie = append(session.Cookie, cookie)
+	}
+
+	return session, nil
+}
+
+// fingerprintOptions adds the identity headers of the profile (user agent, client hints,
+// languages) to the engine headers and returns the CycleTLS fingerprint options
+func fingerprintOptions(headers map[string]string, profile useragent.FingerprintProfile) map[string]string {
+	for name, value := range profile.Headers() {
+		headers[name] = value
+	}
+
+	return map[string]string{
+		"userAgent":   profile.UserAgent,
+		"ja3":         profile.Ja3,
+		"headerOrder": strings.Join(profile.HeaderOrder, ","),
+	}
+}
@@ -0,0 +1,550 @@
+package searchEngine
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"parser/services/captcha"
+	"parser/services/config"
+	"parser/services/errorx"
+	"parser/services/proxyx"
+	"slices"
+	"time"
+)
+
+// tryGenerateSession creates a session bound to a proxy of the pool. The old session is
+// retrusted only on its own proxy, its cookies are never replayed through another one:
+// the first attempt keeps the old proxy while it is healthy, next attempts take a new one.
+func tryGenerateSession(engine SearchEngine, text string, lr string, oldSession *Session) (Session, int, error) {
+	var session Session
+	var solvedCaptcha int
+	var err error
+	var proxy *proxyx.TProxy
+	useProxy := usesProxy(engine)
+
+	for i := 1; i <= config.AttemptsToGenerateSession; i++ {
+		// keep the healthy proxy of the old session on the first attempt, otherwise try another proxy
+		if useProxy && i == 1 && oldSession != nil && oldSession.Proxy != nil && proxyx.IsAvailable(*oldSession.Proxy) {
+			proxy = oldSession.Proxy
+		} else if useProxy {
+			proxyStruct, proxyErr := proxyx.GetProxy()
+
+			if proxyErr != nil {
+				return session, solvedCaptcha, proxyErr
+			}
+
+			proxy = &proxyStruct
+		}
+
+		retrusted := oldSession
+
+		if retrusted != nil && !retrusted.IsBoundTo(proxy) {
+			retrusted = nil
+		}
+
+		var solved int
+		session, solved, err = engine.GenerateSession(text, lr, proxy, retrusted)
+		solvedCaptcha += solved
+
+		if err == nil && config.SessionBindExitIp && proxy != nil {
+			var geo proxyx.Geo
+			geo, err = proxyx.GetGeo(*proxy)
+			session.ExitIP = geo.Query
+		}
+
+		if errors.Is(err, captcha.ErrBudgetExceeded) {
+			return session, solvedCaptcha, errorx.Wrap(errorx.KindCaptcha, "generate session", err)
+		}
+
+		if err != nil {
+			log.Printf("[WARN] %v", err)
+
+			if proxy != nil {
+				proxyx.Report(*proxy, errorx.Wrap(errorx.KindProxyDead, "generate session", err), 0)
+			}
+
+			continue
+		}
+
+		session.Proxy = proxy
+
+		if session.CreatedAt.IsZero() {
+			session.CreatedAt = time.Now()
+		}
+
+		return session, solvedCaptcha, nil
+	}
+
+	return session, solvedCaptcha, errorx.Wrap(transportErrorKind(proxy), "generate session", err)
+}
+
+// usesProxy reports whether requests of the engine go through the proxy pool
+func usesProxy(engine SearchEngine) bool {
+	if direct, ok := engine.(Direct); ok && direct.Direct() {
+		return false
+	}
+
+	return config.UseProxy
+}
+
+// transportErrorKind blames the proxy for transport errors when the request went through it
+func transportErrorKind(proxy *proxyx.TProxy) errorx.Kind {
+	if proxy != nil {
+		return errorx.KindProxyDead
+	}
+
+	return errorx.KindNetwork
+}
+
+// listRun is the state of ParseKeywordsList shared between keywords of the list
+type listRun struct {
+	engine SearchEngine
+	lr     string
+
+	pool         *SessionPool // nil - the run generates its own sessions
+	session      *Session
+	sessionValid bool
+	retireReason string // why the invalidated session must not be leased again (blocked, rotation policy)
+	pageFailed   bool   // the last request of the kept session failed, its exit ip is checked
+
+	solvedCaptcha   int
+	accessSuspended int
+	loadingErrors   int
+	totalPages      int
+	blocks          int
+	markup          MarkupStats
+	errors          map[errorx.Kind]int
+}
+
+func (r *listRun) generateSession(keyword string) error {
+	if r.pool != nil {
+		return r.leaseSession()
+	}
+
+	// the first session of the run may be a trusted one of the previous runs
+	if r.session == nil {
+		if stored := TakeStoredSessions(r.engine, r.lr, 1); len(stored) > 0 {
+			r.session = &stored[0]
+			r.sessionValid = true
+
+			return nil
+		}
+	}
+
+	// new sessions may cost captchas, wait for the captcha budget first
+	if err := captcha.WaitBudget(); err != nil {
+		return errorx.New(errorx.KindCaptcha, "generate session", err)
+	}
+
+	session, solvedCaptcha, err := tryGenerateSession(r.engine, keyword, r.lr, r.session)
+	r.solvedCaptcha += solvedCaptcha
+
+	if err != nil {
+		return err
+	}
+
+	r.session = &session
+	r.sessionValid = true
+
+	return nil
+}
+
+// leaseSession returns the interrupted session to the pool and leases a ready one
+func (r *listRun) leaseSession() error {
+	r.releaseSession()
+	session, err := r.pool.Lease()
+
+	if err != nil {
+		return err
+	}
+
+	r.solvedCaptcha += session.SolvedCaptcha
+	session.SolvedCaptcha = 0
+	r.session = &session
+	r.sessionValid = true
+	r.retireReason = ""
+
+	return nil
+}
+
+// releaseSession returns the session to the pool, a blocked or rotated one is retired. Without
+// a pool a valid session is saved to the store for the next runs.
+func (r *listRun) releaseSession() {
+	if r.session == nil {
+		return
+	}
+
+	if r.pool == nil {
+		if r.sessionValid {
+			StoreSession(r.engine, r.lr, *r.session)
+		}
+
+		return
+	}
+
+	r.pool.Release(*r.session, r.retireReason)
+	r.session = nil
+	r.sessionValid = false
+}
+
+// checkRotation invalidates the session when a rotation policy or a changed exit ip requires it
+func (r *listRun) checkRotation(newKeyword bool) {
+	if !r.sessionValid {
+		return
+	}
+
+	reason := r.session.RotationReason(newKeyword)
+
+	if reason == "" && r.session.ExitIPCheckDue(r.pageFailed) {
+		r.session.ExitIPCheck = r.session.Requests
+		r.pageFailed = false
+
+		if geo, err := proxyx.GetGeo(*r.session.Proxy); err == nil && geo.Query != r.session.ExitIP {
+			reason = fmt.Sprintf("exit ip changed %v -> %v", r.session.ExitIP, geo.Query)
+		}
+	}
+
+	if reason != "" {
+		log.Printf("[INFO] Rotate session (%v)", reason)
+		r.sessionValid = false
+		r.retireReason = reason
+	}
+}
+
+// loadPage fetches the page and reports the outcome to the proxy pool
+func (r *listRun) loadPage(pageUrl string) (TResponse, error) {
+	startTime := time.Now()
+	resp, err := r.fetchPage(pageUrl)
+	r.session.Requests++
+
+	if r.session.Proxy != nil {
+		proxyx.Report(*r.session.Proxy, err, time.Since(startTime))
+	}
+
+	return resp, err
+}
+
+// fetchPage fetches the page and classifies failures: captcha page, ban status,
+// dead proxy and network errors
+func (r *listRun) fetchPage(pageUrl string) (TResponse, error) {
+	resp, err := r.engine.Fetch(pageUrl, r.session, r.session.Proxy)
+
+	if err != nil {
+		r.loadingErrors += 1
+		return resp, errorx.Wrap(transportErrorKind(r.session.Proxy), "fetch", err)
+	}
+
+	if r.engine.IsBlocked(resp) {
+		r.blocks += 1
+		return resp, errorx.Errorf(errorx.KindCaptcha, "fetch", "captcha page %v", resp.FinalUrl)
+	}
+
+	if resp.Status < 400 {
+		return resp, nil
+	}
+
+	log.Printf("[WARN] Page Load error (status: %v)", resp.Status)
+	r.loadingErrors += 1
+
+	switch {
+	case resp.Status == 407:
+		return resp, errorx.Errorf(errorx.KindProxyDead, "fetch", "status %v", resp.Status)
+	case resp.Status < 500:
+		// 403, 429 and other refusals of the engine put the proxy into ban cooldown
+		return resp, errorx.Errorf(errorx.KindBan, "fetch", "status %v", resp.Status)
+	}
+
+	return resp, errorx.Errorf(errorx.KindNetwork, "fetch", "status %v", resp.Status)
+}
+
+// parsePage turns a panic of the engine parser into a parse error
+func (r *listRun) parsePage(html string) (page ParsedPage, err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = errorx.Errorf(errorx.KindParse, "parse page", "%v", rec)
+		}
+	}()
+
+	return r.engine.ParsePage(html), nil
+}
+
+// parseKeyword loads keyword pages retrying errors according to their kind policy.
+// When a policy is exhausted the keyword is returned as failed.
+func (r *listRun) parseKeyword(keyword string, checkpoint Checkpoint) ([]SERPItem, []SERPBlock, *FailureRecord) {
+	parsed := []SERPItem{}
+	parsedBlocks := []SERPBlock{}
+	offset := Position{}
+	attempts := map[errorx.Kind]int{}
+
+	fail := func(page int, err error) *FailureRecord {
+		kind := errorx.KindOf(err)
+		log.Printf("[ERROR] Keyword `%v` failed: %v", keyword, err)
+
+		return &FailureRecord{
+			Engine:   r.engine.Name(),
+			Keyword:  keyword,
+			Page:     page,
+			Kind:     kind,
+			Error:    err.Error(),
+			Attempts: attempts[kind],
+		}
+	}
+
+	for page := 0; page < config.Deep; page++ {
+		r.checkRotation(page == 0 && len(attempts) == 0)
+
+		//generate new session
+		if !r.sessionValid {
+			if err := r.generateSession(keyword); err != nil {
+				err = errorx.Wrap(errorx.KindNetwork, "generate session", err)
+				kind := errorx.KindOf(err)
+				attempts[kind]++
+				r.errors[kind]++
+				policy := errorx.PolicyOf(kind)
+
+				// session generation retries on its own, only waiting for a pooled session is retried here
+				if kind != errorx.KindNoSession || attempts[kind] > policy.Attempts {
+					return nil, nil, fail(page, err)
+				}
+
+				delay := policy.Delay(attempts[kind])
+				log.Printf("[WARN] %v (retry %v/%v in %v)", err, attempts[kind], policy.Attempts, delay)
+				time.Sleep(delay)
+
+				page -= 1
+				continue
+			}
+		}
+
+		pageUrl := r.engine.GetSearchPageUrl(keyword, r.lr, page)
+		log.Printf("[INFO] Parse KW (%v): `%v[%v]`", r.engine.Name(), keyword, page)
+
+		var parsedPage ParsedPage
+		resp, err := r.loadPage(pageUrl)
+
+		if err == nil {
+			parsedPage, err = r.parsePage(resp.Html)
+		}
+
+		if err != nil {
+			kind := errorx.KindOf(err)
+			attempts[kind]++
+			r.errors[kind]++
+			policy := errorx.PolicyOf(kind)
+
+			if attempts[kind] > policy.Attempts {
+				return nil, nil, fail(page, err)
+			}
+
+			delay := policy.Delay(attempts[kind])
+			log.Printf("[WARN] %v (retry %v/%v in %v)", err, attempts[kind], policy.Attempts, delay)
+			time.Sleep(delay)
+			r.pageFailed = kind == errorx.KindNetwork
+
+			//captcha, ban or dead proxy interrupt the session
+			if kind != errorx.KindNetwork && kind != errorx.KindParse {
+				r.sessionValid = false
+				r.retireReason = "blocked"
+				r.accessSuspended += 1
+			}
+
+			page -= 1
+			continue
+		}
+
+		log.Printf("[INFO] Parsed")
+		offset = parsedPage.Shift(offset)
+		r.markup.Check(parsedPage, pageUrl)
+
+		for i := range parsedPage.Items {
+			parsedPage.Items[i].Engine = r.engine.Name()
+			parsedPage.Items[i].Keyword = keyword
+			parsedPage.Items[i].Page = page
+		}
+
+		for i := range parsedPage.Blocks {
+			parsedPage.Blocks[i].Engine = r.engine.Name()
+			parsedPage.Blocks[i].Keyword = keyword
+			parsedPage.Blocks[i].Page = page
+		}
+
+		parsed = append(parsed, parsedPage.Items...)
+		parsedBlocks = append(parsedBlocks, parsedPage.Blocks...)
+		r.totalPages += 1
+
+		if checkpoint != nil {
+			if err := checkpoint.PageDone(r.engine.Name(), keyword, page, len(parsedPage.Items)); err != nil {
+				log.Printf("[WARN] Checkpoint error: %v", err)
+			}
+		}
+
+		//no more results
+		if len(parsedPage.Items) == 0 {
+			break
+		}
+	}
+
+	return parsed, parsedBlocks, nil
+}
+
+// ParseKeywordsList parses keywords with the engine. A keyword whose error exhausted
+// the retry policy of its kind (see errorx.Policies) is returned as failed, the rest
+// of the list goes on.
+// Keywords finished according to the checkpoint are skipped, progress is reported to it
+// (checkpoint may be nil).
+func ParseKeywordsList(engine SearchEngine, keywords []string, lr string, checkpoint Checkpoint) TResult {
+	result := []SERPItem{}
+	resultBlocks := []SERPBlock{}
+	failed := []FailureRecord{}
+	stats := Stats{}
+	startTime := time.Now()
+
+	run := &listRun{
+		engine: engine,
+		lr:     lr,
+		pool:   SessionPoolOf(engine, lr),
+		errors: map[errorx.Kind]int{},
+	}
+
+	if checkpoint != nil {
+		keywords = slices.DeleteFunc(slices.Clone(keywords), func(keyword string) bool {
+			return checkpoint.IsDone(engine.Name(), keyword)
+		})
+	}
+
+	for _, keyword := range keywords {
+		parsed, parsedBlocks, failure := run.parseKeyword(keyword, checkpoint)
+
+		if failure != nil {
+			failed = append(failed, *failure)
+			continue
+		}
+
+		result = append(result, parsed...)
+		resultBlocks = append(resultBlocks, parsedBlocks...)
+
+		if checkpoint != nil {
+			keywordStats := run.takeStats()
+			stats.Merge(keywordStats)
+
+			err := checkpoint.KeywordDone(engine.Name(), keyword, TResult{
+				Items:  parsed,
+				Blocks: parsedBlocks,
+				Stats:  keywordStats,
+			})
+
+			if err != nil {
+				log.Printf("[WARN] Checkpoint error: %v", err)
+			}
+		}
+	}
+
+	run.releaseSession()
+
+	// stats of failed keywords and of the session release
+	restStats := run.takeStats()
+	stats.Merge(restStats)
+	stats.TimeSpend = FormatDuration(time.Since(startTime))
+
+	if checkpoint != nil {
+		if err := checkpoint.AddStats(restStats); err != nil {
+			log.Printf("[WARN] Checkpoint error: %v", err)
+		}
+	}
+
+	return TResult{
+		Items:  result,
+		Blocks: resultBlocks,
+		Stats:  stats,
+		Failed: failed,
+	}
+}
+
+// ParseKeywordsListRoutine parses keywords and sends the result to the channel.
+// Failed keywords are handed to the engine fallback (if any).
+func ParseKeywordsListRoutine(engine SearchEngine, keywords []string, lr string, checkpoint Checkpoint, channel chan TResult) {
+	result := ParseKeywordsList(engine, keywords, lr, checkpoint)
+
+	if withFallback, ok := engine.(WithFallback); ok && len(result.Failed) > 0 {
+		if fallback := withFallback.Fallback(); fallback != nil {
+			log.Printf("[INFO] Parse %v failed keyword(s) with %v", len(result.Failed), fallback.Name())
+
+			failedKeywords := []string{}
+
+			for _, failure := range result.Failed {
+				failedKeywords = append(failedKeywords, failure.Keyword)
+			}
+
+			var fallbackCheckpoint Checkpoint
+
+			if checkpoint != nil {
+				fallbackCheckpoint = primaryCheckpoint{Checkpoint: checkpoint, engine: engine.Name()}
+			}
+
+			fallbackResult := ParseKeywordsList(fallback, failedKeywords, lr, fallbackCheckpoint)
+			result.Items = append(result.Items, fallbackResult.Items...)
+			result.Blocks = append(result.Blocks, fallbackResult.Blocks...)
+			result.Stats.Merge(fallbackResult.Stats)
+			result.Failed = fallbackResult.Failed
+		}
+	}
+
+	channel <- result
+}
+
+// primaryCheckpoint records the fallback progress under the primary engine name, so a resumed
+// run skips keywords finished by the fallback and the journal keeps a single result of them
+type primaryCheckpoint struct {
+	Checkpoint
+	engine string
+}
+
+func (c primaryCheckpoint) IsDone(_ string, keyword string) bool {
+	return c.Checkpoint.IsDone(c.engine, keyword)
+}
+
+func (c primaryCheckpoint) PageDone(_ string, keyword string, page int, items int) error {
+	return c.Checkpoint.PageDone(c.engine, keyword, page, items)
+}
+
+func (c primaryCheckpoint) KeywordDone(_ string, keyword string, result TResult) error {
+	return c.Checkpoint.KeywordDone(c.engine, keyword, result)
+}
+
+// takeStats returns the counters gathered since the previous call and resets them
+func (r *listRun) takeStats() Stats {
+	stats := Stats{
+		TotalPages:         r.totalPages,
+		TotalCaptchaSolved: r.solvedCaptcha,
+		AccessSuspended:    r.accessSuspended,
+		LoadingErrors:      r.loadingErrors,
+		Errors:             r.errors,
+		Engines: map[string]EngineStats{
+			r.engine.Name(): {
+				Pages:  r.totalPages,
+				Errors: r.loadingErrors,
+				Blocks: r.blocks,
+				Markup: r.markup,
+			},
+		},
+	}
+
+	r.totalPages = 0
+	r.solvedCaptcha = 0
+	r.accessSuspended = 0
+	r.loadingErrors = 0
+	r.blocks = 0
+	r.markup = MarkupStats{}
+	r.errors = map[errorx.Kind]int{}
+
+	return stats
+}
+
+// FormatDuration formats duration as hh:mm:ss
+func FormatDuration(elapsed time.Duration) string {
+	hours := int(elapsed.Hours())
+	minutes := int(elapsed.Minutes()) % 60
+	seconds := int(elapsed.Seconds()) % 60
+
+	return fmt.Sprintf("%02d:%02d:%02d", hours, minutes, seconds)
+}
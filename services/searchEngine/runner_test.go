@@ -0,0 +1,219 @@
+package searchEngine
+
+import (
+	"errors"
+	"fmt"
+	"github.com/chromedp/cdproto/network"
+	"maps"
+	"parser/services/errorx"
+	"parser/services/proxyx"
+	"sync"
+	"testing"
+)
+
+// fakeResponse is a scripted outcome of a fetch
+type fakeResponse struct {
+	resp TResponse
+	err  error
+}
+
+// fakeEngine serves scripted responses in order, then pages with a single item.
+// Html "captcha" is a block page, html "broken" panics the parser. Generated sessions
+// have a cookie with their number.
+type fakeEngine struct {
+	mu         sync.Mutex
+	responses  []fakeResponse
+	sessions   int
+	sessionErr error
+	fetchGate  chan struct{} // nil - fetches are not held
+}
+
+func (e *fakeEngine) Name() string {
+	return "fake"
+}
+
+func (e *fakeEngine) Direct() bool {
+	return true
+}
+
+func (e *fakeEngine) GetSearchPageUrl(text string, lr string, page int) string {
+	return "https://fake.test/search?text=" + text
+}
+
+func (e *fakeEngine) Fetch(pageUrl string, session *Session, proxy *proxyx.TProxy) (TResponse, error) {
+	if e.fetchGate != nil {
+		<-e.fetchGate
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if len(e.responses) == 0 {
+		return TResponse{Html: "https://a.ru/", Status: 200, FinalUrl: pageUrl}, nil
+	}
+
+	next := e.responses[0]
+	e.responses = e.responses[1:]
+
+	return next.resp, next.err
+}
+
+func (e *fakeEngine) IsBlocked(resp TResponse) bool {
+	return resp.Html == "captcha"
+}
+
+func (e *fakeEngine) ParsePage(html string) ParsedPage {
+	if html == "broken" {
+		panic("unexpected markup")
+	}
+
+	return ParsedPage{
+		Items:  []SERPItem{{Pos: 1, AbsPos: 1, URL: html, Title: "title"}},
+		Length: 1,
+	}
+}
+
+func (e *fakeEngine) GenerateSession(text string, lr string, proxy *proxyx.TProxy, oldSession *Session) (Session, int, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.sessionErr != nil {
+		return Session{}, 0, e.sessionErr
+	}
+
+	e.sessions++
+
+	return Session{Cookie: []*network.Cookie{{Name: "uid", Value: fmt.Sprint(e.sessions), Session: true}}}, 0, nil
+}
+
+func (e *fakeEngine) generated() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.sessions
+}
+
+// noBackoff retries at once for the test duration
+func noBackoff(t *testing.T) {
+	policies := maps.Clone(errorx.Policies)
+
+	t.Cleanup(func() {
+		errorx.Policies = policies
+	})
+
+	errorx.Policies = map[errorx.Kind]errorx.RetryPolicy{}
+
+	for kind, policy := range policies {
+		policy.Backoff = 0
+		errorx.Policies[kind] = policy
+	}
+}
+
+func repeat(response fakeResponse, n int) []fakeResponse {
+	responses := []fakeResponse{}
+
+	for i := 0; i < n; i++ {
+		responses = append(responses, response)
+	}
+
+	return responses
+}
+
+func TestParseKeywordRetries(t *testing.T) {
+	noBackoff(t)
+
+	captchaPage := fakeResponse{resp: TResponse{Html: "captcha", Status: 200}}
+	banned := fakeResponse{resp: TResponse{Status: 403}}
+	deadProxy := fakeResponse{resp: TResponse{Status: 407}}
+	serverError := fakeResponse{resp: TResponse{Status: 502}}
+	transport := fakeResponse{err: errors.New("connection reset")}
+	brokenPage := fakeResponse{resp: TResponse{Html: "broken", Status: 200}}
+
+	// a policy is exhausted by the attempt after its last retry
+	captchaAttempts := errorx.PolicyOf(errorx.KindCaptcha).Attempts + 1
+	banAttempts := errorx.PolicyOf(errorx.KindBan).Attempts + 1
+	networkAttempts := errorx.PolicyOf(errorx.KindNetwork).Attempts + 1
+	parseAttempts := errorx.PolicyOf(errorx.KindParse).Attempts + 1
+
+	tests := []struct {
+		name      string
+		responses []fakeResponse
+		failed    errorx.Kind // "" - the keyword is parsed
+		attempts  int
+		sessions  int // generated sessions: every captcha, ban and dead proxy interrupts the session
+	}{
+		{"no errors", nil, "", 0, 1},
+		{"network retried", []fakeResponse{serverError, transport}, "", 0, 1},
+		{"captcha retried", []fakeResponse{captchaPage}, "", 0, 2},
+		{"mixed kinds counted apart", []fakeResponse{captchaPage, banned, serverError, deadProxy}, "", 0, 4},
+		{"captcha exhausted", repeat(captchaPage, captchaAttempts), errorx.KindCaptcha, captchaAttempts, captchaAttempts},
+		{"ban exhausted", repeat(banned, banAttempts), errorx.KindBan, banAttempts, banAttempts},
+		{"network exhausted", repeat(serverError, networkAttempts), errorx.KindNetwork, networkAttempts, 1},
+		{"parse error", repeat(brokenPage, parseAttempts), errorx.KindParse, parseAttempts, 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Chdir(t.TempDir())
+			engine := &fakeEngine{responses: test.responses}
+			result := ParseKeywordsList(engine, []string{"phone"}, "213", nil)
+
+			if sessions := engine.generated(); sessions != test.sessions {
+				t.Errorf("expected %v generated session(s), got %v", test.sessions, sessions)
+			}
+
+			if test.failed == "" {
+				if len(result.Failed) != 0 || len(result.Items) != 1 {
+					t.Fatalf("expected the keyword parsed, got %v item(s) and failures %+v", len(result.Items), result.Failed)
+				}
+
+				return
+			}
+
+			if len(result.Failed) != 1 || len(result.Items) != 0 {
+				t.Fatalf("expected the keyword failed, got %v item(s) and failures %+v", len(result.Items), result.Failed)
+			}
+
+			failure := result.Failed[0]
+			expected := FailureRecord{Engine: "fake", Keyword: "phone", Page: 0, Kind: test.failed, Error: failure.Error, Attempts: test.attempts}
+
+			if failure != expected || failure.Error == "" {
+				t.Errorf("expected %+v, got %+v", expected, failure)
+			}
+
+			if errorsOfKind := result.Stats.Errors[test.failed]; errorsOfKind != test.attempts {
+				t.Errorf("expected %v %v error(s) in stats, got %v", test.attempts, test.failed, errorsOfKind)
+			}
+		})
+	}
+}
+
+func TestParseKeywordsListGoesOn(t *testing.T) {
+	t.Chdir(t.TempDir())
+	noBackoff(t)
+
+	brokenPage := fakeResponse{resp: TResponse{Html: "broken", Status: 200}}
+	engine := &fakeEngine{responses: repeat(brokenPage, errorx.PolicyOf(errorx.KindParse).Attempts+1)}
+	result := ParseKeywordsList(engine, []string{"first", "second"}, "213", nil)
+
+	if len(result.Failed) != 1 || result.Failed[0].Keyword != "first" {
+		t.Errorf("expected the first keyword failed, got %+v", result.Failed)
+	}
+
+	if len(result.Items) != 1 || result.Items[0].Keyword != "second" || result.Items[0].Engine != "fake" {
+		t.Errorf("expected an item of the second keyword, got %+v", result.Items)
+	}
+}
+
+func TestParseKeywordSessionFailure(t *testing.T) {
+	t.Chdir(t.TempDir())
+	noBackoff(t)
+
+	engine := &fakeEngine{sessionErr: errors.New("no cookies")}
+	result := ParseKeywordsList(engine, []string{"phone"}, "213", nil)
+
+	// no proxy: session generation errors are network ones, retried by tryGenerateSession only
+	if len(result.Failed) != 1 || result.Failed[0].Kind != errorx.KindNetwork || result.Failed[0].Attempts != 1 {
+		t.Errorf("expected a network failure after one session attempt, got %+v", result.Failed)
+	}
+}
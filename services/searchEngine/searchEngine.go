@@ -0,0 +1,391 @@
+/**
+ * package searchEngine
+ *
+ * Search engine abstraction. Engine-specific packages (searchYandex, ...) implement
+ * SearchEngine and register themselves, the keyword runner, stats and result types
+ * are shared between all of them.
+ */
+
+package searchEngine
+
+import (
+	"fmt"
+	"log"
+	"parser/services/captcha"
+	"parser/services/errorx"
+	"parser/services/proxyx"
+	"parser/services/selectors"
+	"parser/services/warmup"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Search Engine Results Page Item
+type SERPItem struct {
+	Engine  string `json:"engine"`
+	Keyword string `json:"keyword"`
+	Page    int    `json:"page"`
+	Pos     int    `json:"pos"`     // organic rank across pages
+	AbsPos  int    `json:"abs_pos"` // rank among all SERP blocks (ads, wizards, organic) across pages
+	URL     string `json:"url"`
+	Domain  string `json:"domain"`
+	Title   string `json:"title"`
+	Text    string `json:"text"`
+}
+
+// SERP block types
+const (
+	BlockAd               = "ad"
+	BlockFact             = "fact" // featured snippet / fact answer
+	BlockImages           = "images"
+	BlockVideo            = "video"
+	BlockNews             = "news"
+	BlockMaps             = "maps" // maps organizations
+	BlockPeopleAlsoSearch = "people_also_search"
+	BlockRelated          = "related" // related queries
+)
+
+// SERPBlock is a non-organic SERP feature (ads, wizards, related queries ...)
+type SERPBlock struct {
+	Engine    string     `json:"engine"`
+	Keyword   string     `json:"keyword"`
+	Type      string     `json:"type"`
+	Page      int        `json:"page"`
+	Pos       int        `json:"pos"`                 // rank among all SERP blocks across pages
+	Placement string     `json:"placement,omitempty"` // top / bottom (ads)
+	URL       string     `json:"url,omitempty"`
+	Domain    string     `json:"domain,omitempty"`
+	Title     string     `json:"title,omitempty"`
+	Text      string     `json:"text,omitempty"`
+	Items     []SERPItem `json:"items,omitempty"` // block entries: organizations, videos, queries ...
+}
+
+// ParsedPage is the organic list and SERP features of a page.
+// Engines fill page-local positions (starting from 1), the runner shifts them by the
+// running offset of previous pages.
+type ParsedPage struct {
+	Items  []SERPItem  `json:"items"`
+	Blocks []SERPBlock `json:"blocks"`
+	Length int         `json:"length"` // number of all SERP blocks on the page (organic included)
+
+	// selector registry matches (engines without a registry leave it empty)
+	SelectorVersion string            `json:"-"`
+	Selectors       selectors.Matches `json:"-"`
+}
+
+// Position is the running offset of a keyword's pages
+type Position struct {
+	Organic  int
+	Absolute int
+}
+
+// Shift converts page-local positions to positions across pages and returns
+// the offset for the next page.
+func (p *ParsedPage) Shift(offset Position) Position {
+	for i := range p.Items {
+		p.Items[i].Pos += offset.Organic
+		p.Items[i].AbsPos += offset.Absolute
+	}
+
+	for i := range p.Blocks {
+		p.Blocks[i].Pos += offset.Absolute
+	}
+
+	return Position{
+		Organic:  offset.Organic + len(p.Items),
+		Absolute: offset.Absolute + p.Length,
+	}
+}
+
+type Stats struct {
+	TotalPages         int    `json:"total_pages_loaded"`
+	TotalCaptchaSolved int    `json:"total_captcha_solved"`
+	AccessSuspended    int    `json:"access_suspended"`
+	LoadingErrors      int    `json:"loading_errors"`
+	TimeSpend          string `json:"time_spent"`
+	FailedKeywords     int    `json:"failed_keywords"`
+
+	Errors  map[errorx.Kind]int              `json:"errors_by_kind,omitempty"` // page / session errors by kind (retried ones included)
+	Engines map[string]EngineStats           `json:"engines"`
+	Captcha map[string]captcha.ProviderStats `json:"captcha,omitempty"` // solver submissions, solves, wrong solutions and cost by provider
+	Warmup  map[string]warmup.StepStats      `json:"warmup,omitempty"`  // warm-up step runs and captcha rate of the sessions warmed with them
+}
+
+// EngineStats are per-engine counters of the run report
+type EngineStats struct {
+	Pages  int         `json:"pages"`
+	Errors int         `json:"errors"`
+	Blocks int         `json:"blocks"`
+	Markup MarkupStats `json:"markup"`
+}
+
+// Markup statuses
+const (
+	MarkupOk    = "ok"
+	MarkupDrift = "drift"
+)
+
+// MarkupStats shows which selectors matched and pages that look like a layout change
+type MarkupStats struct {
+	Status          string            `json:"status"`
+	SelectorVersion string            `json:"selector_version,omitempty"`
+	Selectors       selectors.Matches `json:"selectors,omitempty"`
+	SuspiciousPages int               `json:"suspicious_pages"`
+	Alerts          []string          `json:"alerts,omitempty"`
+}
+
+// FailureRecord is a keyword that could not be parsed: the error kind that exhausted
+// its retry policy and the last error
+type FailureRecord struct {
+	Engine   string      `json:"engine"`
+	Keyword  string      `json:"keyword"`
+	Page     int         `json:"page"`
+	Kind     errorx.Kind `json:"kind"`
+	Error    string      `json:"error"`
+	Attempts int         `json:"attempts"`
+}
+
+type TResult struct {
+	Items  []SERPItem
+	Blocks []SERPBlock
+	Stats  Stats
+	Failed []FailureRecord
+}
+
+// TResponse is a loaded search page
+type TResponse struct {
+	Html     string
+	Status   int
+	FinalUrl string
+}
+
+// SearchEngine is implemented by every supported search engine.
+//
+// Pagination is part of the URL builder: the runner asks for pages 0..config.Deep-1
+// and stops early when a page has no organic results.
+type SearchEngine interface {
+	// Name is the engine name used in config and stats
+	Name() string
+
+	// GetSearchPageUrl builds the search page URL for the text, region and page number
+	GetSearchPageUrl(text string, lr string, page int) string
+
+	// Fetch loads the page using session cookies and proxy (both may be nil)
+	Fetch(pageUrl string, session *Session, proxy *proxyx.TProxy) (TResponse, error)
+
+	// IsBlocked reports whether the response is a captcha / ban page
+	IsBlocked(resp TResponse) bool
+
+	// ParsePage extracts organic results and SERP features with page-local positions
+	ParsePage(html string) ParsedPage
+
+	// GenerateSession creates (or retrusts) a session, returns the number of solved captchas
+	GenerateSession(text string, lr string, proxy *proxyx.TProxy, oldSession *Session) (Session, int, error)
+}
+
+// Checkpoint receives progress of the runner (see package journal). KeywordDone gets the stats
+// gathered since the previous finished keyword, the rest of the list stats go to AddStats.
+type Checkpoint interface {
+	IsDone(engine string, keyword string) bool
+	PageDone(engine string, keyword string, page int, items int) error
+	KeywordDone(engine string, keyword string, result TResult) error
+	AddStats(stats Stats) error
+}
+
+// Direct is implemented by engines that never go through a proxy (APIs bound to the server ip)
+type Direct interface {
+	Direct() bool
+}
+
+// WithFallback is implemented by engines that hand failed keywords over to another engine
+type WithFallback interface {
+	// Fallback returns the fallback engine or nil when fallback is disabled
+	Fallback() SearchEngine
+}
+
+var (
+	enginesMu sync.RWMutex
+	engines   = map[string]SearchEngine{}
+)
+
+// Register makes the engine available by its name. Engine packages call it from init().
+func Register(engine SearchEngine) {
+	enginesMu.Lock()
+	defer enginesMu.Unlock()
+
+	engines[engine.Name()] = engine
+}
+
+// Get returns registered engine by name
+func Get(name string) (SearchEngine, error) {
+	enginesMu.RLock()
+	defer enginesMu.RUnlock()
+
+	engine, ok := engines[name]
+
+	if !ok {
+		return nil, fmt.Errorf("unknown search engine `%v` (available: %v)", name, strings.Join(registeredNames(), ", "))
+	}
+
+	return engine, nil
+}
+
+// GetList returns engines by comma separated names
+func GetList(names string) ([]SearchEngine, error) {
+	list := []SearchEngine{}
+
+	for _, name := range strings.Split(names, ",") {
+		engine, err := Get(strings.TrimSpace(name))
+
+		if err != nil {
+			return nil, err
+		}
+
+		list = append(list, engine)
+	}
+
+	return list, nil
+}
+
+func registeredNames() []string {
+	list := []string{}
+
+	for name := range engines {
+		list = append(list, name)
+	}
+
+	sort.Strings(list)
+
+	return list
+}
+
+// Merge adds counters of other stats to s. TimeSpend and FailedKeywords (totals of the
+// run report) are not merged.
+func (s *Stats) Merge(other Stats) {
+	s.TotalPages += other.TotalPages
+	s.TotalCaptchaSolved += other.TotalCaptchaSolved
+	s.AccessSuspended += other.AccessSuspended
+	s.LoadingErrors += other.LoadingErrors
+
+	for kind, count := range other.Errors {
+		if s.Errors == nil {
+			s.Errors = map[errorx.Kind]int{}
+		}
+
+		s.Errors[kind] += count
+	}
+
+	for name, providerStats := range other.Captcha {
+		if s.Captcha == nil {
+			s.Captcha = map[string]captcha.ProviderStats{}
+		}
+
+		current := s.Captcha[name]
+		current.Merge(providerStats)
+		s.Captcha[name] = current
+	}
+
+	for name, stepStats := range other.Warmup {
+		if s.Warmup == nil {
+			s.Warmup = map[string]warmup.StepStats{}
+		}
+
+		current := s.Warmup[name]
+		current.Merge(stepStats)
+		s.Warmup[name] = current
+	}
+
+	for name, engineStats := range other.Engines {
+		if s.Engines == nil {
+			s.Engines = map[string]EngineStats{}
+		}
+
+		current := s.Engines[name]
+		current.Pages += engineStats.Pages
+		current.Errors += engineStats.Errors
+		current.Blocks += engineStats.Blocks
+		current.Markup.Merge(engineStats.Markup)
+		s.Engines[name] = current
+	}
+}
+
+// maxMarkupAlerts limits alerts kept in stats, the first ones are the interesting
+const maxMarkupAlerts = 20
+
+// Check counts page selector matches and raises an alert when the page has serp items
+// but no titles or urls were parsed
+func (m *MarkupStats) Check(page ParsedPage, pageUrl string) {
+	if m.Status == "" {
+		m.Status = MarkupOk
+	}
+
+	if page.SelectorVersion != "" {
+		m.SelectorVersion = page.SelectorVersion
+	}
+
+	if len(page.Selectors) > 0 {
+		if m.Selectors == nil {
+			m.Selectors = selectors.Matches{}
+		}
+
+		m.Selectors.Merge(page.Selectors)
+	}
+
+	if page.Length == 0 {
+		return
+	}
+
+	titles := 0
+	urls := 0
+
+	for _, item := range page.Items {
+		if item.Title != "" {
+			titles++
+		}
+
+		if item.URL != "" {
+			urls++
+		}
+	}
+
+	if titles > 0 && urls > 0 {
+		return
+	}
+
+	alert := fmt.Sprintf("%v serp item(s), %v title(s), %v url(s): %v", page.Length, titles, urls, pageUrl)
+	log.Printf("[ALERT] Markup drift: %v", alert)
+
+	m.Status = MarkupDrift
+	m.SuspiciousPages++
+
+	if len(m.Alerts) < maxMarkupAlerts {
+		m.Alerts = append(m.Alerts, alert)
+	}
+}
+
+func (m *MarkupStats) Merge(other MarkupStats) {
+	if m.Status != MarkupDrift && other.Status != "" {
+		m.Status = other.Status
+	}
+
+	if other.SelectorVersion != "" {
+		m.SelectorVersion = other.SelectorVersion
+	}
+
+	if len(other.Selectors) > 0 {
+		if m.Selectors == nil {
+			m.Selectors = selectors.Matches{}
+		}
+
+		m.Selectors.Merge(other.Selectors)
+	}
+
+	m.SuspiciousPages += other.SuspiciousPages
+
+	for _, alert := range other.Alerts {
+		if len(m.Alerts) < maxMarkupAlerts {
+			m.Alerts = append(m.Alerts, alert)
+		}
+	}
+}
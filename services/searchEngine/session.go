@@ -0,0 +1,101 @@
+package searchEngine
+
+import (
+	"github.com/chromedp/cdproto/network"
+	"parser/services/config"
+	"parser/services/proxyx"
+	"parser/services/useragent"
+	"strings"
+	"time"
+)
+
+// Session is a cookie jar bound to the proxy (exit ip) and fingerprint it was created with.
+// Requests of the session go through its proxy only.
+type Session struct {
+	Cookie      []*network.Cookie
+	Proxy       *proxyx.TProxy
+	ExitIP      string
+	ExitIPCheck int                          // Requests at the last exit ip check
+	Fingerprint useragent.FingerprintProfile // shared by the browser and CycleTLS requests of the session
+	CreatedAt   time.Time
+	Requests    int
+
+	SolvedCaptcha int // captchas solved to create the session (counted by the worker that leases it)
+}
+
+// session rotation policies (config.SessionRotation), a blocked session is always rotated
+const (
+	RotateOnBlock  = "on-block"
+	RotateKeyword  = "keyword"  // new session for every keyword
+	RotateRequests = "requests" // after config.SessionRotateRequests requests
+	RotateTime     = "time"     // after config.SessionRotateAfter
+)
+
+// NewSession returns an empty session bound to the proxy. A retrusted session keeps
+// the fingerprint of the old one.
+func NewSession(proxy *proxyx.TProxy, oldSession *Session) Session {
+	session := Session{
+		Proxy:       proxy,
+		Fingerprint: useragent.NewFingerprint(),
+		CreatedAt:   time.Now(),
+	}
+
+	if oldSession != nil && oldSession.Fingerprint.UserAgent != "" {
+		session.Fingerprint = oldSession.Fingerprint
+	}
+
+	return session
+}
+
+// ExitIPCheckDue reports whether the exit ip of the session bound to it must be checked:
+// every config.SessionExitIpCheckRequests requests or after a failed request
+func (s *Session) ExitIPCheckDue(failed bool) bool {
+	if s.ExitIP == "" || s.Proxy == nil {
+		return false
+	}
+
+	return failed || s.Requests-s.ExitIPCheck >= config.SessionExitIpCheckRequests
+}
+
+// IsBoundTo reports whether the session was created with the proxy
+func (s *Session) IsBoundTo(proxy *proxyx.TProxy) bool {
+	if s.Proxy == nil || proxy == nil {
+		return s.Proxy == nil && proxy == nil
+	}
+
+	return proxyx.StructToStr(*s.Proxy) == proxyx.StructToStr(*proxy)
+}
+
+// RotationReason returns why the session must be rotated by the configured policies
+// before the next request ("" - keep the session)
+func (s *Session) RotationReason(newKeyword bool) string {
+	for _, policy := range strings.Split(config.SessionRotation, ",") {
+		switch strings.TrimSpace(policy) {
+		case RotateOnBlock:
+		case RotateKeyword:
+			if newKeyword && s.Requests > 0 {
+				return RotateKeyword
+			}
+		case RotateRequests:
+			if config.SessionRotateRequests > 0 && s.Requests >= config.SessionRotateRequests {
+				return RotateRequests
+			}
+		case RotateTime:
+			if config.SessionRotateAfter > 0 && time.Since(s.CreatedAt) >= config.SessionRotateAfter {
+				return RotateTime
+			}
+		}
+	}
+
+	return ""
+}
+
+func CookieToString(cookie []*network.Cookie) string {
+	var cookiePairs []string
+
+	for _, c := range cookie {
+		cookiePairs = append(cookiePairs, c.Name+"="+c.Value)
+	}
+
+	return strings.Join(cookiePairs, "; ")
+}
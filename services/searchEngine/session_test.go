@@ -0,0 +1,31 @@
+package searchEngine
+
+import (
+	"parser/services/config"
+	"parser/services/proxyx"
+	"testing"
+)
+
+func TestExitIPCheckDue(t *testing.T) {
+	proxy := &proxyx.TProxy{Protocol: "http", Host: "10.0.0.1", Port: "8080"}
+	every := config.SessionExitIpCheckRequests
+
+	tests := []struct {
+		name     string
+		session  Session
+		failed   bool
+		expected bool
+	}{
+		{"not bound", Session{Proxy: proxy, Requests: every}, true, false},
+		{"no proxy", Session{ExitIP: "1.1.1.1", Requests: every}, true, false},
+		{"checked recently", Session{Proxy: proxy, ExitIP: "1.1.1.1", Requests: every + 1, ExitIPCheck: 2}, false, false},
+		{"failed request", Session{Proxy: proxy, ExitIP: "1.1.1.1", Requests: 3, ExitIPCheck: 2}, true, true},
+		{"check is due", Session{Proxy: proxy, ExitIP: "1.1.1.1", Requests: every + 2, ExitIPCheck: 2}, false, true},
+	}
+
+	for _, test := range tests {
+		if due := test.session.ExitIPCheckDue(test.failed); due != test.expected {
+			t.Errorf("%v: expected %v, got %v", test.name, test.expected, due)
+		}
+	}
+}
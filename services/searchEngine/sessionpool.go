@@ -0,0 +1,225 @@
+package searchEngine
+
+import (
+	"errors"
+	"log"
+	"parser/services/captcha"
+	"parser/services/config"
+	"parser/services/errorx"
+	"sync"
+	"time"
+)
+
+// SessionPool keeps config.SessionPoolSize trusted sessions of the engine ready. Sessions are
+// generated (captchas included) by background warmers and leased to workers, a released
+// session goes back to the pool unless it was blocked or hit a rotation limit, then it is
+// retired and replaced in the background.
+type SessionPool struct {
+	engine SearchEngine
+	lr     string
+
+	idle    chan Session
+	warmers chan struct{} // limits parallel session generation
+	done    chan struct{}
+
+	mu        sync.Mutex // orders puts to idle with Close, so no session is left in a closed pool
+	closed    bool
+	closeOnce sync.Once
+}
+
+var (
+	poolsMu sync.Mutex
+	pools   = map[string]*SessionPool{}
+)
+
+// SessionPoolOf returns the pool of the engine and region shared by the workers,
+// nil when pools are disabled (config.SessionPoolSize is 0)
+func SessionPoolOf(engine SearchEngine, lr string) *SessionPool {
+	if config.SessionPoolSize <= 0 {
+		return nil
+	}
+
+	poolsMu.Lock()
+	defer poolsMu.Unlock()
+
+	key := engine.Name() + "\x00" + lr
+
+	if pools[key] == nil {
+		pools[key] = NewSessionPool(engine, lr, config.SessionPoolSize)
+	}
+
+	return pools[key]
+}
+
+// CloseSessionPools stops warmers of all pools
+func CloseSessionPools() {
+	poolsMu.Lock()
+	defer poolsMu.Unlock()
+
+	for key, pool := range pools {
+		pool.Close()
+		delete(pools, key)
+	}
+}
+
+// NewSessionPool starts warming `size` sessions
+func NewSessionPool(engine SearchEngine, lr string, size int) *SessionPool {
+	p := &SessionPool{
+		engine:  engine,
+		lr:      lr,
+		idle:    make(chan Session, size),
+		warmers: make(chan struct{}, max(config.SessionPoolWarmers, 1)),
+		done:    make(chan struct{}),
+	}
+
+	// stored sessions are probed in the background as well, warmers generate the rest
+	go func() {
+		stored := TakeStoredSessions(engine, lr, size)
+
+		for _, session := range stored {
+			p.put(session)
+		}
+
+		log.Printf("[INFO] Warm %v %v session(s)", size-len(stored), engine.Name())
+
+		for i := len(stored); i < size; i++ {
+			go p.warm()
+		}
+	}()
+
+	return p
+}
+
+// warm generates a session and puts it to the pool, retrying until it succeeds or the pool is closed
+func (p *SessionPool) warm() {
+	for attempt := 1; ; attempt++ {
+		select {
+		case <-p.done:
+			return
+		case p.warmers <- struct{}{}: // block slot
+		}
+
+		session, err := p.generate()
+		<-p.warmers // free slot
+
+		if err == nil {
+			if !p.put(session) {
+				StoreSession(p.engine, p.lr, session)
+			}
+
+			return
+		}
+
+		if errors.Is(err, captcha.ErrBudgetExceeded) {
+			log.Printf("[ALERT] Session pool %v: %v, warmer stopped", p.engine.Name(), err)
+			return
+		}
+
+		delay := errorx.PolicyOf(errorx.KindOf(err)).Delay(attempt)
+		log.Printf("[WARN] Session pool %v: %v (retry in %v)", p.engine.Name(), err, delay)
+
+		select {
+		case <-p.done:
+			return
+		case <-time.After(delay):
+		}
+	}
+}
+
+func (p *SessionPool) generate() (Session, error) {
+	if err := captcha.WaitBudget(); err != nil {
+		return Session{}, err
+	}
+
+	session, solvedCaptcha, err := tryGenerateSession(p.engine, config.SessionPoolWarmText, p.lr, nil)
+	session.SolvedCaptcha = solvedCaptcha
+
+	return session, err
+}
+
+// Lease returns a ready session, waiting up to config.SessionPoolWait for the warmers
+func (p *SessionPool) Lease() (Session, error) {
+	timeout := time.After(config.SessionPoolWait)
+
+	for {
+		select {
+		case session := <-p.idle:
+			if reason := session.RotationReason(false); reason != "" {
+				log.Printf("[INFO] Retire pooled session (%v)", reason)
+				p.replace()
+				continue
+			}
+
+			return session, nil
+		case <-p.done:
+			return Session{}, errorx.Errorf(errorx.KindNetwork, "lease session", "session pool is closed")
+		case <-timeout:
+			return Session{}, errorx.Errorf(errorx.KindNoSession, "lease session", "no %v session ready in %v", p.engine.Name(), config.SessionPoolWait)
+		}
+	}
+}
+
+// Release returns the session to the pool. A session with a retire reason (blocked, rotated
+// by a policy or a changed exit ip) or the one hit a rotation limit is retired and replaced.
+func (p *SessionPool) Release(session Session, retireReason string) {
+	if retireReason == "" {
+		retireReason = session.RotationReason(false)
+	}
+
+	if retireReason != "" {
+		log.Printf("[INFO] Retire pooled session (%v)", retireReason)
+		p.replace()
+		return
+	}
+
+	if !p.put(session) {
+		p.replace()
+	}
+}
+
+// put adds the session to the idle ones, after Close it goes back to the store.
+// Returns false when the pool is full.
+func (p *SessionPool) put(session Session) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		StoreSession(p.engine, p.lr, session)
+		return true
+	}
+
+	select {
+	case p.idle <- session:
+		return true
+	default:
+		return false
+	}
+}
+
+func (p *SessionPool) replace() {
+	select {
+	case <-p.done:
+	default:
+		go p.warm()
+	}
+}
+
+// Close stops the warmers and saves idle sessions to the store, sessions put to the pool
+// later (released, warmed or probed stored ones) are saved to the store as well
+func (p *SessionPool) Close() {
+	p.closeOnce.Do(func() {
+		p.mu.Lock()
+		p.closed = true
+		close(p.done)
+		p.mu.Unlock()
+
+		for {
+			select {
+			case session := <-p.idle:
+				StoreSession(p.engine, p.lr, session)
+			default:
+				return
+			}
+		}
+	})
+}
@@ -0,0 +1,149 @@
+package searchEngine
+
+import (
+	"parser/services/errorx"
+	"parser/services/storage"
+	"parser/services/useragent"
+	"testing"
+	"time"
+)
+
+// newTestPool starts a pool of the fake engine in a storage of a temp dir
+func newTestPool(t *testing.T, engine *fakeEngine, size int) *SessionPool {
+	t.Chdir(t.TempDir())
+
+	p := NewSessionPool(engine, "213", size)
+	t.Cleanup(p.Close)
+
+	return p
+}
+
+func waitFor(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second * 5)
+
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("no %v in 5s", what)
+		}
+
+		time.Sleep(time.Millisecond * 10)
+	}
+}
+
+func storedSessions(t *testing.T) []StoredSession {
+	t.Helper()
+
+	storeMu.Lock()
+	defer storeMu.Unlock()
+
+	list, err := readStoredSessions("fake")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return list
+}
+
+func cookieOf(session Session) string {
+	if len(session.Cookie) == 0 {
+		return ""
+	}
+
+	return session.Cookie[0].Value
+}
+
+func TestSessionPoolLeaseRelease(t *testing.T) {
+	engine := &fakeEngine{}
+	p := newTestPool(t, engine, 1)
+
+	session, err := p.Lease()
+
+	if err != nil || cookieOf(session) != "1" {
+		t.Fatalf("expected the warmed session, got %q (%v)", cookieOf(session), err)
+	}
+
+	// released session is leased again as is
+	session.Requests = 3
+	p.Release(session, "")
+	session, err = p.Lease()
+
+	if err != nil || cookieOf(session) != "1" || session.Requests != 3 {
+		t.Fatalf("expected the released session, got %q with %v request(s) (%v)", cookieOf(session), session.Requests, err)
+	}
+
+	// retired session is replaced by a new one
+	p.Release(session, "blocked")
+	session, err = p.Lease()
+
+	if err != nil || cookieOf(session) != "2" || session.Requests != 0 {
+		t.Fatalf("expected a new session, got %q with %v request(s) (%v)", cookieOf(session), session.Requests, err)
+	}
+
+	if generated := engine.generated(); generated != 2 {
+		t.Errorf("expected 2 generated sessions, got %v", generated)
+	}
+}
+
+func TestSessionPoolClose(t *testing.T) {
+	p := newTestPool(t, &fakeEngine{}, 2)
+
+	leased, err := p.Lease()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor(t, "idle session", func() bool { return len(p.idle) == 1 })
+	p.Close()
+
+	if stored := storedSessions(t); len(stored) != 1 {
+		t.Fatalf("expected the idle session stored, got %v stored session(s)", len(stored))
+	}
+
+	// released after Close: back to the store
+	p.Release(leased, "")
+
+	if stored := storedSessions(t); len(stored) != 2 {
+		t.Errorf("expected the released session stored, got %v stored session(s)", len(stored))
+	}
+
+	if _, err := p.Lease(); !errorx.Is(err, errorx.KindNetwork) {
+		t.Errorf("expected a closed pool error, got %v", err)
+	}
+}
+
+func TestSessionPoolStoredAfterClose(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	err := storage.WriteFile(sessionStoreFile("fake"), []StoredSession{{
+		Lr:          "213",
+		Cookie:      []StoredCookie{{Name: "uid", Value: "stored", Session: true}},
+		Proxy:       "http://10.0.0.1:8080",
+		Fingerprint: useragent.FingerprintProfile{UserAgent: "Mozilla/5.0"},
+		CreatedAt:   time.Now(),
+	}})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the probe of the stored session is held till the pool is closed
+	engine := &fakeEngine{fetchGate: make(chan struct{})}
+	p := NewSessionPool(engine, "213", 1)
+	waitFor(t, "stored session taken", func() bool { return len(storedSessions(t)) == 0 })
+	p.Close()
+	close(engine.fetchGate)
+
+	waitFor(t, "probed session stored back", func() bool { return len(storedSessions(t)) == 1 })
+
+	if stored := storedSessions(t)[0]; stored.Cookie[0].Value != "stored" || stored.Requests != 1 {
+		t.Errorf("expected the probed stored session, got %+v", stored)
+	}
+
+	if len(p.idle) != 0 || engine.generated() != 0 {
+		t.Errorf("expected no session put to the closed pool, got %v idle, %v generated", len(p.idle), engine.generated())
+	}
+}
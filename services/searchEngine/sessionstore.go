@@ -0,0 +1,322 @@
+package searchEngine
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/chromedp/cdproto/network"
+	"log"
+	"parser/services/config"
+	"parser/services/proxyx"
+	"parser/services/storage"
+	"parser/services/useragent"
+	"slices"
+	"sync"
+	"time"
+)
+
+// StoredSession is a session saved between runs (storage/sessions/<engine>.json)
+type StoredSession struct {
+	Lr          string                       `json:"lr"`
+	Cookie      []StoredCookie               `json:"cookie"`
+	Proxy       string                       `json:"proxy,omitempty"`
+	ExitIP      string                       `json:"exit_ip,omitempty"`
+	Fingerprint useragent.FingerprintProfile `json:"fingerprint"`
+	CreatedAt   time.Time                    `json:"created_at"`
+	Requests    int                          `json:"requests"`
+}
+
+// StoredCookie keeps the fields of network.Cookie the sessions use, cdproto enums
+// can't be decoded when empty
+type StoredCookie struct {
+	Name     string  `json:"name"`
+	Value    string  `json:"value"`
+	Domain   string  `json:"domain"`
+	Path     string  `json:"path"`
+	Expires  float64 `json:"expires"` // seconds since the UNIX epoch
+	HTTPOnly bool    `json:"http_only"`
+	Secure   bool    `json:"secure"`
+	Session  bool    `json:"session"`
+	SameSite string  `json:"same_site,omitempty"`
+}
+
+var storeMu sync.Mutex
+
+func sessionStoreFile(engineName string) string {
+	return "sessions/" + engineName + ".json"
+}
+
+// IsExpired reports whether the session is older than config.SessionStoreMaxAge or
+// one of its persistent cookies expired
+func (s *Session) IsExpired() bool {
+	if config.SessionStoreMaxAge > 0 && time.Since(s.CreatedAt) > config.SessionStoreMaxAge {
+		return true
+	}
+
+	now := float64(time.Now().Unix())
+
+	for _, cookie := range s.Cookie {
+		if !cookie.Session && cookie.Expires > 0 && cookie.Expires < now {
+			return true
+		}
+	}
+
+	return false
+}
+
+// StoreSession saves the session of the engine and region for the next runs
+func StoreSession(engine SearchEngine, lr string, session Session) {
+	if !config.SessionStore || len(session.Cookie) == 0 || session.IsExpired() {
+		return
+	}
+
+	stored := StoredSession{
+		Lr:          lr,
+		Cookie:      toStoredCookies(session.Cookie),
+		ExitIP:      session.ExitIP,
+		Fingerprint: session.Fingerprint,
+		CreatedAt:   session.CreatedAt,
+		Requests:    session.Requests,
+	}
+
+	if session.Proxy != nil {
+		stored.Proxy = proxyx.StructToStr(*session.Proxy)
+	}
+
+	storeMu.Lock()
+	defer storeMu.Unlock()
+
+	list, err := readStoredSessions(engine.Name())
+
+	if err != nil {
+		log.Printf("[WARN] Session store: %v", err)
+
+		// the file could not be read nor kept aside, it is not overwritten
+		if storage.Exists(sessionStoreFile(engine.Name())) {
+			return
+		}
+	}
+
+	// prune expired sessions of all regions
+	list = slices.DeleteFunc(list, func(s StoredSession) bool {
+		session := Session{Cookie: fromStoredCookies(s.Cookie), CreatedAt: s.CreatedAt}
+		return session.IsExpired()
+	})
+
+	if err := storage.WriteFile(sessionStoreFile(engine.Name()), append(list, stored)); err != nil {
+		log.Printf("[WARN] Session store: %v", err)
+	}
+}
+
+// TakeStoredSessions returns up to n stored sessions of the engine and region that passed
+// a probe search. Taken, expired and failed sessions are removed from the store.
+func TakeStoredSessions(engine SearchEngine, lr string, n int) []Session {
+	sessions := []Session{}
+
+	if !config.SessionStore || n <= 0 {
+		return sessions
+	}
+
+	candidates := takeStoredSessions(engine.Name(), lr)
+
+	for i, stored := range candidates {
+		if len(sessions) == n {
+			for _, rest := range candidates[i:] {
+				restoreSession(engine.Name(), rest)
+			}
+
+			break
+		}
+
+		session, err := stored.session()
+
+		if err == nil && session.IsExpired() {
+			err = fmt.Errorf("expired")
+		}
+
+		if err == nil {
+			err = ProbeSession(engine, lr, &session)
+		}
+
+		if err != nil {
+			log.Printf("[INFO] Prune stored %v session of %v: %v", engine.Name(), stored.CreatedAt.Format(time.DateTime), err)
+			continue
+		}
+
+		sessions = append(sessions, session)
+	}
+
+	if len(sessions) > 0 {
+		log.Printf("[INFO] Reuse %v stored %v session(s)", len(sessions), engine.Name())
+	}
+
+	return sessions
+}
+
+// ProbeSession checks the session with a cheap search (config.SessionProbeText) through its proxy
+func ProbeSession(engine SearchEngine, lr string, session *Session) error {
+	resp, err := engine.Fetch(engine.GetSearchPageUrl(config.SessionProbeText, lr, 0), session, session.Proxy)
+	session.Requests++
+
+	switch {
+	case err != nil:
+		return err
+	case engine.IsBlocked(resp):
+		return fmt.Errorf("captcha page %v", resp.FinalUrl)
+	case resp.Status >= 400:
+		return fmt.Errorf("status %v", resp.Status)
+	}
+
+	return nil
+}
+
+// session restores the session, its proxy must match config.UseProxy and sessions stored
+// before fingerprint profiles are dropped
+func (s StoredSession) session() (Session, error) {
+	session := Session{
+		Cookie:      fromStoredCookies(s.Cookie),
+		ExitIP:      s.ExitIP,
+		Fingerprint: s.Fingerprint,
+		CreatedAt:   s.CreatedAt,
+		Requests:    s.Requests,
+	}
+
+	// cookies were issued to another identity
+	if s.Fingerprint.UserAgent == "" {
+		return session, fmt.Errorf("no fingerprint profile")
+	}
+
+	if (s.Proxy != "") != config.UseProxy {
+		return session, fmt.Errorf("proxy `%v` doesn't match config.UseProxy", s.Proxy)
+	}
+
+	if s.Proxy != "" {
+		proxy, err := proxyx.StrToStruct(s.Proxy)
+
+		if err != nil {
+			return session, err
+		}
+
+		session.Proxy = &proxy
+	}
+
+	return session, nil
+}
+
+func toStoredCookies(cookies []*network.Cookie) []StoredCookie {
+	list := []StoredCookie{}
+
+	for _, c := range cookies {
+		list = append(list, StoredCookie{
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   c.Domain,
+			Path:     c.Path,
+			Expires:  c.Expires,
+			HTTPOnly: c.HTTPOnly,
+			Secure:   c.Secure,
+			Session:  c.Session,
+			SameSite: c.SameSite.String(),
+		})
+	}
+
+	return list
+}
+
+func fromStoredCookies(cookies []StoredCookie) []*network.Cookie {
+	list := []*network.Cookie{}
+
+	for _, c := range cookies {
+		list = append(list, &network.Cookie{
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   c.Domain,
+			Path:     c.Path,
+			Expires:  c.Expires,
+			HTTPOnly: c.HTTPOnly,
+			Secure:   c.Secure,
+			Session:  c.Session,
+			SameSite: network.CookieSameSite(c.SameSite),
+		})
+	}
+
+	return list
+}
+
+// takeStoredSessions removes sessions of the region from the store and returns them
+func takeStoredSessions(engineName string, lr string) []StoredSession {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+
+	list, err := readStoredSessions(engineName)
+
+	if err != nil {
+		log.Printf("[WARN] Session store: %v", err)
+		return nil
+	}
+
+	taken := []StoredSession{}
+	rest := []StoredSession{}
+
+	for _, stored := range list {
+		if stored.Lr == lr {
+			taken = append(taken, stored)
+		} else {
+			rest = append(rest, stored)
+		}
+	}
+
+	if len(taken) > 0 {
+		if err := storage.WriteFile(sessionStoreFile(engineName), rest); err != nil {
+			log.Printf("[WARN] Session store: %v", err)
+		}
+	}
+
+	return taken
+}
+
+func restoreSession(engineName string, stored StoredSession) {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+
+	list, err := readStoredSessions(engineName)
+
+	if err != nil {
+		log.Printf("[WARN] Session store: %v", err)
+
+		if storage.Exists(sessionStoreFile(engineName)) {
+			return
+		}
+	}
+
+	if err := storage.WriteFile(sessionStoreFile(engineName), append(list, stored)); err != nil {
+		log.Printf("[WARN] Session store: %v", err)
+	}
+}
+
+// readStoredSessions reads the store file, storeMu must be held. A file that can't be decoded
+// is kept aside (<file>.corrupt-<time>) for inspection and the store starts over.
+func readStoredSessions(engineName string) ([]StoredSession, error) {
+	list := []StoredSession{}
+
+	if !storage.Exists(sessionStoreFile(engineName)) {
+		return list, nil
+	}
+
+	data, err := storage.ReadFile(sessionStoreFile(engineName))
+
+	if err != nil {
+		return list, err
+	}
+
+	if err := json.Unmarshal([]byte(data), &list); err != nil {
+		corrupt := sessionStoreFile(engineName) + ".corrupt-" + time.Now().Format("20060102-150405")
+
+		if renameErr := storage.Rename(sessionStoreFile(engineName), corrupt); renameErr != nil {
+			return []StoredSession{}, fmt.Errorf("%v: %w (can't keep it aside: %v)", sessionStoreFile(engineName), err, renameErr)
+		}
+
+		return []StoredSession{}, fmt.Errorf("%v: %w (kept as %v)", sessionStoreFile(engineName), err, corrupt)
+	}
+
+	return list, nil
+}
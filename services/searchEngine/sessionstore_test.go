@@ -0,0 +1,151 @@
+package searchEngine
+
+import (
+	"github.com/chromedp/cdproto/network"
+	"os"
+	"parser/services/config"
+	"parser/services/storage"
+	"parser/services/useragent"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func storedSession(lr string, value string, createdAt time.Time) StoredSession {
+	return StoredSession{
+		Lr:          lr,
+		Cookie:      []StoredCookie{{Name: "uid", Value: value, Session: true}},
+		Fingerprint: useragent.FingerprintProfile{UserAgent: "Mozilla/5.0"},
+		CreatedAt:   createdAt,
+	}
+}
+
+func writeStore(t *testing.T, list []StoredSession) {
+	t.Helper()
+
+	if err := storage.WriteFile(sessionStoreFile("fake"), list); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func storedValues(list []StoredSession) []string {
+	values := []string{}
+
+	for _, stored := range list {
+		values = append(values, stored.Cookie[0].Value)
+	}
+
+	return values
+}
+
+func TestStoredCookies(t *testing.T) {
+	cookies := []*network.Cookie{
+		{Name: "yandexuid", Value: "123", Domain: ".yandex.ru", Path: "/", Expires: 1893456000, HTTPOnly: true, Secure: true, SameSite: network.CookieSameSiteNone},
+		{Name: "spravka", Value: "dD0x", Domain: ".yandex.ru", Path: "/", Session: true},
+	}
+
+	restored := fromStoredCookies(toStoredCookies(cookies))
+
+	if !reflect.DeepEqual(restored, cookies) {
+		t.Errorf("cookies changed by the store:\nexpected %+v %+v\ngot %+v %+v", *cookies[0], *cookies[1], *restored[0], *restored[1])
+	}
+}
+
+func TestSessionIsExpired(t *testing.T) {
+	past := float64(time.Now().Add(-time.Hour).Unix())
+	future := float64(time.Now().Add(time.Hour).Unix())
+
+	tests := []struct {
+		name     string
+		session  Session
+		expected bool
+	}{
+		{"fresh", Session{CreatedAt: time.Now(), Cookie: []*network.Cookie{{Expires: future}}}, false},
+		{"too old", Session{CreatedAt: time.Now().Add(-config.SessionStoreMaxAge - time.Minute)}, config.SessionStoreMaxAge > 0},
+		{"expired cookie", Session{CreatedAt: time.Now(), Cookie: []*network.Cookie{{Expires: future}, {Expires: past}}}, true},
+		{"expired session cookie", Session{CreatedAt: time.Now(), Cookie: []*network.Cookie{{Expires: past, Session: true}}}, false},
+	}
+
+	for _, test := range tests {
+		if expired := test.session.IsExpired(); expired != test.expected {
+			t.Errorf("%v: expected %v, got %v", test.name, test.expected, expired)
+		}
+	}
+}
+
+func TestStoreSessionPrunes(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	if config.SessionStoreMaxAge <= 0 {
+		t.Skip("sessions don't expire by age")
+	}
+
+	old := time.Now().Add(-config.SessionStoreMaxAge - time.Minute)
+	writeStore(t, []StoredSession{
+		storedSession("213", "old", old),
+		storedSession("2", "other region", time.Now()),
+		storedSession("2", "old other region", old),
+	})
+
+	StoreSession(&fakeEngine{}, "213", Session{
+		Cookie:    []*network.Cookie{{Name: "uid", Value: "new", Session: true}},
+		CreatedAt: time.Now(),
+	})
+
+	expected := []string{"other region", "new"}
+
+	if values := storedValues(storedSessions(t)); !reflect.DeepEqual(values, expected) {
+		t.Errorf("expected %v, got %v", expected, values)
+	}
+}
+
+func TestTakeStoredSessionsRegion(t *testing.T) {
+	t.Chdir(t.TempDir())
+	writeStore(t, []StoredSession{
+		storedSession("213", "first", time.Now()),
+		storedSession("2", "other region", time.Now()),
+		storedSession("213", "second", time.Now()),
+	})
+
+	taken := takeStoredSessions("fake", "213")
+
+	if values := storedValues(taken); !reflect.DeepEqual(values, []string{"first", "second"}) {
+		t.Errorf("expected sessions of the region taken, got %v", values)
+	}
+
+	if values := storedValues(storedSessions(t)); !reflect.DeepEqual(values, []string{"other region"}) {
+		t.Errorf("expected sessions of other regions kept, got %v", values)
+	}
+
+	if taken := takeStoredSessions("fake", "213"); len(taken) != 0 {
+		t.Errorf("expected taken sessions removed from the store, got %v", storedValues(taken))
+	}
+}
+
+func TestStoreSessionKeepsCorruptFile(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	if err := storage.WriteFile(sessionStoreFile("fake"), `[{"lr":"213","cookie":[{"na`); err != nil {
+		t.Fatal(err)
+	}
+
+	StoreSession(&fakeEngine{}, "213", Session{
+		Cookie:    []*network.Cookie{{Name: "uid", Value: "new", Session: true}},
+		CreatedAt: time.Now(),
+	})
+
+	corrupt, err := filepath.Glob("storage/" + sessionStoreFile("fake") + ".corrupt-*")
+
+	if err != nil || len(corrupt) != 1 {
+		t.Fatalf("expected the corrupt store kept aside, got %v (%v)", corrupt, err)
+	}
+
+	if data, err := os.ReadFile(corrupt[0]); err != nil || string(data) != `[{"lr":"213","cookie":[{"na` {
+		t.Errorf("expected the corrupt store unchanged, got %q (%v)", data, err)
+	}
+
+	if values := storedValues(storedSessions(t)); !reflect.DeepEqual(values, []string{"new"}) {
+		t.Errorf("expected a new store with the session, got %v", values)
+	}
+}
@@ -0,0 +1,37 @@
+package searchGoogle
+
+import (
+	"parser/services/proxyx"
+	"parser/services/searchEngine"
+)
+
+// Engine is the google search engine (host is set by config.GoogleHost)
+type Engine struct{}
+
+func init() {
+	searchEngine.Register(Engine{})
+}
+
+func (Engine) Name() string {
+	return "google"
+}
+
+func (Engine) GetSearchPageUrl(text string, lr string, page int) string {
+	return GetSearchPageUrl(text, lr, page)
+}
+
+func (Engine) Fetch(pageUrl string, session *searchEngine.Session, proxy *proxyx.TProxy) (searchEngine.TResponse, error) {
+	return searchEngine.Fetch(pageUrl, GetHeaders(), session, proxy)
+}
+
+func (Engine) IsBlocked(resp searchEngine.TResponse) bool {
+	return IsBlocked(resp.FinalUrl, resp.Html)
+}
+
+func (Engine) ParsePage(html string) searchEngine.ParsedPage {
+	return ParsePage(html)
+}
+
+func (Engine) GenerateSession(text string, lr string, proxy *proxyx.TProxy, oldSession *searchEngine.Session) (searchEngine.Session, int, error) {
+	return GenerateSession(text, lr, proxy, oldSession)
+}
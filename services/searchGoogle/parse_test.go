@@ -0,0 +1,97 @@
+package searchGoogle
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"parser/services/searchEngine"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// go test ./services/searchGoogle -update rewrites golden files after an intended markup change
+var update = flag.Bool("update", false, "update golden files")
+
+type golden struct {
+	Blocked bool                    `json:"blocked"`
+	Page    searchEngine.ParsedPage `json:"page"`
+}
+
+func readFixture(t *testing.T, name string) string {
+	t.Helper()
+
+	data, err := os.ReadFile("testdata/" + name)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(data)
+}
+
+func TestParsePageGolden(t *testing.T) {
+	fixtures, err := filepath.Glob("testdata/*.html")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, fixture := range fixtures {
+		name := strings.TrimSuffix(filepath.Base(fixture), ".html")
+
+		t.Run(name, func(t *testing.T) {
+			html := readFixture(t, filepath.Base(fixture))
+			actual, err := json.MarshalIndent(golden{
+				Blocked: IsBlocked("", html),
+				Page:    ParsePage(html),
+			}, "", "  ")
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			goldenPath := "testdata/" + name + ".golden.json"
+
+			if *update {
+				if err := os.WriteFile(goldenPath, append(actual, '\n'), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			expected, err := os.ReadFile(goldenPath)
+
+			if err != nil {
+				t.Fatalf("no golden file (run with -update): %v", err)
+			}
+
+			if !bytes.Equal(bytes.TrimSpace(expected), bytes.TrimSpace(actual)) {
+				t.Errorf("parsed page differs from %v\nexpected:\n%s\nactual:\n%s", goldenPath, expected, actual)
+			}
+		})
+	}
+}
+
+func TestIsBlocked(t *testing.T) {
+	cases := []struct {
+		name     string
+		finalUrl string
+		fixture  string
+		expected bool
+	}{
+		{"sorry redirect", "https://www.google.com/sorry/index?continue=https://www.google.com/search", "sorry.html", true},
+		{"recaptcha without redirect", "https://www.google.com/search?q=phone", "sorry.html", true},
+		{"organic", "https://www.google.com/search?q=phone", "organic.html", false},
+		// the SERP of a query about recaptcha has the markers as text
+		{"serp about recaptcha", "https://www.google.com/search?q=g-recaptcha", "recaptcha_query.html", false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if actual := IsBlocked(c.finalUrl, readFixture(t, c.fixture)); actual != c.expected {
+				t.Errorf("expected %v, got %v", c.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package searchGoogle
+
+import (
+	"encoding/base64"
+	"github.com/PuerkitoBio/goquery"
+	"net/url"
+	"parser/services/config"
+	"parser/services/searchEngine"
+	"strconv"
+	"strings"
+)
+
+const pageSize = 10
+
+// regions maps yandex `lr` region ids to google canonical location names (used for uule)
+var regions = map[string]string{
+	"213": "Moscow,Moscow,Russia",
+	"2":   "Saint Petersburg,Saint Petersburg,Russia",
+	"46":  "Kirov,Kirov Oblast,Russia",
+	"54":  "Yekaterinburg,Sverdlovsk Oblast,Russia",
+	"65":  "Novosibirsk,Novosibirsk Oblast,Russia",
+	"43":  "Kazan,Republic of Tatarstan,Russia",
+	"47":  "Nizhny Novgorod,Nizhny Novgorod Oblast,Russia",
+	"35":  "Krasnodar,Krasnodar Krai,Russia",
+}
+
+// blockMarkers are page fragments of the reCAPTCHA page (served without /sorry/ redirect too).
+// A SERP of a query about recaptcha contains them as text, so a page with a marker is
+// checked against captchaSelector.
+var blockMarkers = []string{
+	"g-recaptcha",
+	"captcha-form",
+}
+
+// captchaSelector are elements of the reCAPTCHA page markup
+const captchaSelector = "form#captcha-form, div.g-recaptcha"
+
+// GetUule кодирует каноническое название местоположения в параметр uule
+func GetUule(location string) string {
+	const key = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_"
+
+	if location == "" || len(location) >= len(key) {
+		return ""
+	}
+
+	return "w+CAIQICI" + string(key[len(location)]) + base64.StdEncoding.EncodeToString([]byte(location))
+}
+
+// GetSearchPageUrl формирует URL поиска Google с гео-таргетингом hl/gl/uule.
+// Регион lr задаётся в формате Яндекса и переводится в местоположение uule,
+// для неизвестных регионов используется config.GoogleLocation.
+func GetSearchPageUrl(text string, lr string, page int) string {
+	pageUrl, _ := url.Parse("https://" + config.GoogleHost + "/search")
+	params := url.Values{}
+	params.Add("q", text)
+	params.Add("hl", config.GoogleHl)
+	params.Add("gl", config.GoogleGl)
+	params.Add("num", strconv.Itoa(pageSize))
+
+	location, ok := regions[lr]
+
+	if !ok {
+		location = config.GoogleLocation
+	}
+
+	if uule := GetUule(location); uule != "" {
+		params.Add("uule", uule)
+	}
+
+	if page > 0 {
+		params.Add("start", strconv.Itoa(page*pageSize))
+	}
+
+	pageUrl.RawQuery = params.Encode()
+
+	return pageUrl.String()
+}
+
+func GetHeaders() map[string]string {
+	return map[string]string{
+		"Accept":                    "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8,application/signed-exchange;v=b3;q=0.7",
+		"Accept-Encoding":           "gzip, deflate, br, zstd",
+		"Accept-Language":           "ru-RU,ru;q=0.9,en-US;q=0.8,en;q=0.7",
+		"Cache-Control":             "no-cache",
+		"Pragma":                    "no-cache",
+		"Sec-Fetch-Dest":            "document",
+		"Sec-Fetch-Mode":            "navigate",
+		"Sec-Fetch-Site":            "same-origin",
+		"Sec-Fetch-User":            "?1",
+		"Upgrade-Insecure-Requests": "1",
+		"Referer":                   "https://" + config.GoogleHost + "/",
+	}
+}
+
+// IsBlocked detects the "unusual traffic" reCAPTCHA page by the /sorry/ url or its markup
+func IsBlocked(finalUrl string, html string) bool {
+	if strings.Contains(finalUrl, "/sorry/") {
+		return true
+	}
+
+	for _, marker := range blockMarkers {
+		if strings.Contains(html, marker) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+
+			return err == nil && doc.Find(captchaSelector).Length() > 0
+		}
+	}
+
+	return false
+}
+
+type blockSelector struct {
+	selector  string
+	blockType string
+	placement string
+}
+
+// blockSelectors are google SERP features, a node matching several selectors gets
+// the type of the first one
+var blockSelectors = []blockSelector{
+	{"#tads", searchEngine.BlockAd, "top"},
+	{"#bottomads", searchEngine.BlockAd, "bottom"},
+	{".xpdopen .kp-blk", searchEngine.BlockFact, ""},
+	{"g-scrolling-carousel", searchEngine.BlockVideo, ""},
+	{"div[data-attrid=news]", searchEngine.BlockNews, ""},
+	{".related-question-pair", searchEngine.BlockPeopleAlsoSearch, ""},
+	{"#bres", searchEngine.BlockRelated, ""},
+}
+
+func ParsePage(html string) searchEngine.ParsedPage {
+	doc, _ := goquery.NewDocumentFromReader(strings.NewReader(html))
+
+	result := searchEngine.ParsedPage{
+		Items:  []searchEngine.SERPItem{},
+		Blocks: []searchEngine.SERPBlock{},
+	}
+	seen := map[string]bool{}
+	selectors := []string{"#search a:has(h3)"}
+	containers := []string{".xpdopen"}
+
+	for _, block := range blockSelectors {
+		selectors = append(selectors, block.selector)
+		containers = append(containers, block.selector)
+	}
+
+	// goquery returns the union of selectors in document order
+	pos := 0
+	doc.Find(strings.Join(selectors, ", ")).Each(func(i int, node *goquery.Selection) {
+		for _, blockSelector := range blockSelectors {
+			if !node.Is(blockSelector.selector) {
+				continue
+			}
+
+			pos++
+			block := searchEngine.SERPBlock{
+				Type:      blockSelector.blockType,
+				Pos:       pos,
+				Placement: blockSelector.placement,
+				Title:     strings.TrimSpace(node.Find("h3, [role=heading]").First().Text()),
+			}
+
+			node.Find("a[href]").Each(func(j int, aNode *goquery.Selection) {
+				linkUrl, _ := aNode.Attr("href")
+				u, _ := url.Parse(cleanUrl(linkUrl))
+				block.Items = append(block.Items, searchEngine.SERPItem{
+					Pos:    j + 1,
+					URL:    u.String(),
+					Domain: u.Hostname(),
+					Title:  strings.TrimSpace(aNode.Text()),
+				})
+			})
+
+			result.Blocks = append(result.Blocks, block)
+			return
+		}
+
+		// organic result, links of the blocks are block items
+		if node.Closest(strings.Join(containers, ", ")).Length() > 0 {
+			return
+		}
+
+		linkUrl, _ := node.Attr("href")
+		linkUrl = cleanUrl(linkUrl)
+
+		if linkUrl == "" || seen[linkUrl] {
+			return
+		}
+
+		pos++
+		seen[linkUrl] = true
+		u, _ := url.Parse(linkUrl)
+		container := node.Closest("div.g, div.MjjYud")
+		textEl := container.Find("div.VwiC3b, div[data-sncf], div.IsZvec").First()
+
+		result.Items = append(result.Items, searchEngine.SERPItem{
+			Pos:    len(result.Items) + 1,
+			AbsPos: pos,
+			URL:    u.String(),
+			Domain: u.Hostname(),
+			Title:  node.Find("h3").First().Text(),
+			Text:   textEl.Text(),
+		})
+	})
+
+	result.Length = pos
+
+	return result
+}
+
+// cleanUrl unwraps /url?q= redirects and drops google internal links
+func cleanUrl(href string) string {
+	if strings.HasPrefix(href, "/url?") {
+		u, err := url.Parse(href)
+
+		if err != nil {
+			return ""
+		}
+
+		href = u.Query().Get("q")
+	}
+
+	if !strings.HasPrefix(href, "http://") && !strings.HasPrefix(href, "https://") {
+		return ""
+	}
+
+	return href
+}
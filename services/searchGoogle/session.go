@@ -0,0 +1,72 @@
+package searchGoogle
+
+import (
+	"context"
+	"errors"
+	"github.com/chromedp/chromedp"
+	"log"
+	browserCtl "parser/services/browserctl"
+	"parser/services/errorx"
+	"parser/services/proxyx"
+	"parser/services/searchEngine"
+	"time"
+)
+
+const CaptchaError string = "Google reCAPTCHA"
+
+// GenerateSession opens the search page in the browser and collects google cookies.
+// reCAPTCHA is not solved: the error makes the runner try another proxy.
+func GenerateSession(text string, lr string, proxy *proxyx.TProxy, oldSession *searchEngine.Session) (searchEngine.Session, int, error) {
+	proxyStr := ""
+
+	if proxy != nil {
+		proxyStr = proxyx.StructToStr(*proxy)
+	}
+
+	if oldSession != nil {
+		log.Printf("[INFO] Retrust google session (proxy=%v)", proxyStr)
+	} else {
+		log.Printf("[INFO] Generate new google session (proxy=%v)", proxyStr)
+	}
+
+	session := searchEngine.NewSession(proxy, oldSession)
+	ctx, cancelAll := browserCtl.GetContext(context.Background(), browserCtl.GetContextOptions{
+		Proxy:       proxy,
+		Fingerprint: &session.Fingerprint,
+	})
+	defer cancelAll()
+
+	var locationHref string
+	var html string
+
+	// a retrusted session continues with its cookies
+	if oldSession != nil {
+		err := chromedp.Run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+			return browserCtl.SetCookiesFromNetworkCookies(ctx, oldSession.Cookie)
+		}))
+
+		if err != nil {
+			return searchEngine.Session{}, 0, err
+		}
+	}
+
+	err := chromedp.Run(ctx,
+		chromedp.Navigate(GetSearchPageUrl(text, lr, 0)),
+		chromedp.WaitReady("body"),
+		chromedp.Sleep(time.Second),
+		chromedp.Location(&locationHref),
+		chromedp.OuterHTML("html", &html),
+	)
+
+	if err != nil {
+		return searchEngine.Session{}, 0, err
+	}
+
+	if IsBlocked(locationHref, html) {
+		return searchEngine.Session{}, 0, errorx.New(errorx.KindCaptcha, "google session", errors.New(CaptchaError))
+	}
+
+	session.Cookie = browserCtl.GetCookies(ctx)
+
+	return session, 0, nil
+}
@@ -0,0 +1,302 @@
+package searchYandex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/chromedp/cdproto/input"
+	"github.com/chromedp/chromedp"
+	"log"
+	"parser/services/captcha"
+	"parser/services/config"
+	"parser/services/errorx"
+	"parser/services/geometry"
+	"parser/services/storage"
+	"strings"
+	"time"
+)
+
+// variants of the showcaptcha page
+const (
+	variantPassed     = ""           // not a captcha page
+	variantCheckbox   = "checkbox"   // "I am not a robot" button
+	variantSilhouette = "silhouette" // click the image in the order of the task silhouettes (any number of points)
+	variantText       = "text"       // type the text of the image
+	variantSlider     = "slider"     // move the slider until the image is assembled
+	variantUnknown    = "unknown"
+)
+
+// detectCaptchaJs returns the variant of the page and image urls of the task
+const detectCaptchaJs = `(function(){
+	var q = function(s){ return document.querySelector(s) };
+	var src = function(s){ var el = q(s); return el ? el.src : '' };
+
+	if (!location.href.includes('showcaptcha')) return {variant: ''};
+
+	if (q('.AdvancedCaptcha-SilhouetteTask img')) {
+		return {variant: 'silhouette', image: src('.AdvancedCaptcha-ImageWrapper img'), instruction: src('.AdvancedCaptcha-SilhouetteTask img')};
+	}
+
+	if (q('.CaptchaSlider') || q('.AdvancedCaptcha_kaleidoscope')) {
+		return {variant: 'slider', image: src('.AdvancedCaptcha-ImageWrapper img') || src('.AdvancedCaptcha-View img')};
+	}
+
+	if (q('.Textinput-Control') || q('input[name="rep"]')) {
+		return {variant: 'text', image: src('.AdvancedCaptcha-ImageWrapper img') || src('.AdvancedCaptcha-View img') || src('img.AdvancedCaptcha-Image')};
+	}
+
+	if (q('#js-button')) return {variant: 'checkbox'};
+
+	return {variant: 'unknown'};
+})()`
+
+// rectJs returns the bounding box of the first element of the selector
+const rectJs = `(function(){
+	var el = document.querySelector(%q);
+	if (!el) return {left: 0, top: 0, width: 0, height: 0};
+	var r = el.getBoundingClientRect();
+	return {left: r.left, top: r.top, width: r.width, height: r.height};
+})()`
+
+// naturalWidthJs returns the width of the captcha image file (solution points are in its pixels)
+const naturalWidthJs = `(function(){
+	var el = document.querySelector('.AdvancedCaptcha-ImageWrapper img') || document.querySelector('.AdvancedCaptcha-View img');
+	return el ? el.naturalWidth : 0;
+})()`
+
+type captchaPage struct {
+	Variant     string `json:"variant"`
+	Image       string `json:"image"`
+	Instruction string `json:"instruction"`
+}
+
+// SolveCaptcha solves captchas until the search page is shown, at most config.CaptchaMaxRounds
+// times. Pages of unknown variants are saved to storage/captcha/unknown for analysis. A session
+// left on the captcha page gets a KindCaptcha error, captcha.ErrBudgetExceeded is returned as is.
+func SolveCaptcha(ctx context.Context) (int, error) {
+	var solvedCaptchaCount = 0
+
+	for round := 0; round < config.CaptchaMaxRounds; round++ {
+		var page captchaPage
+
+		err := chromedp.Run(ctx, chromedp.Evaluate(detectCaptchaJs, &page))
+
+		if err != nil {
+			return solvedCaptchaCount, notPassed("detection failed: %v", err)
+		}
+
+		switch page.Variant {
+		case variantPassed:
+			log.Printf("[INFO] Captcha passed")
+			return solvedCaptchaCount, nil
+		case variantCheckbox:
+			log.Printf("[INFO] Captcha offered")
+			chromedp.Run(ctx,
+				// click button "i am not a robot"
+				chromedp.Evaluate("document.getElementById('js-button')?.click()", nil),
+				chromedp.Sleep(time.Second*2),
+			)
+			continue
+		case variantUnknown:
+			return solvedCaptchaCount, notPassed("unknown variant, saved to %v", saveCaptchaPage(ctx))
+		}
+
+		log.Printf("[INFO] Solve %v captcha", page.Variant)
+
+		solution, err := solveCaptchaPage(ctx, page)
+
+		if errors.Is(err, captcha.ErrBudgetExceeded) {
+			return solvedCaptchaCount, err
+		}
+
+		if err != nil {
+			return solvedCaptchaCount, notPassed("%v captcha not solved: %v", page.Variant, err)
+		}
+
+		solvedCaptchaCount++
+
+		var currentURL string
+		chromedp.Run(ctx, chromedp.Location(&currentURL))
+
+		if strings.Contains(currentURL, "showcaptcha") {
+			if err := captcha.ReportBad(solution); err != nil {
+				log.Printf("[WARN] Can't report wrong captcha solution: %v", err)
+			}
+		}
+	}
+
+	return solvedCaptchaCount, notPassed("%v rounds are over", config.CaptchaMaxRounds)
+}
+
+// notPassed is the error of a session left on the captcha page
+func notPassed(format string, args ...any) error {
+	return errorx.Errorf(errorx.KindCaptcha, "solve captcha", "captcha not passed: "+format, args...)
+}
+
+// applyTimeout limits the page reads and clicks of a solution, the solving itself is limited
+// by config.CaptchaTimeout
+const applyTimeout = time.Second * 20
+
+// solveCaptchaPage solves the task with the configured solvers, applies the solution and submits it
+func solveCaptchaPage(ctx context.Context, page captchaPage) (captcha.Solution, error) {
+	task, err := loadCaptchaTask(page)
+
+	if err != nil {
+		return captcha.Solution{}, err
+	}
+
+	solution, err := captcha.Solve(task)
+
+	if err != nil {
+		return solution, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, applyTimeout)
+	defer cancel()
+
+	var rect geometry.Rectangle
+	var naturalWidth float64
+
+	err = chromedp.Run(ctx,
+		chromedp.EvaluateAsDevTools(fmt.Sprintf(rectJs, ".AdvancedCaptcha-ImageWrapper img, .AdvancedCaptcha-View img"), &rect),
+		chromedp.Evaluate(naturalWidthJs, &naturalWidth),
+	)
+
+	if err != nil {
+		return solution, err
+	}
+
+	// scale of the displayed image
+	scale := 1.0
+
+	if naturalWidth > 0 && rect.Width > 0 {
+		scale = rect.Width / naturalWidth
+	}
+
+	var actions []chromedp.Action
+
+	switch page.Variant {
+	case variantSilhouette:
+		// modify relative coords to absolute coords
+		for _, point := range solution.Points {
+			actions = append(actions,
+				chromedp.MouseClickXY(rect.Left+point.X*scale, rect.Top+point.Y*scale),
+				chromedp.Sleep(time.Second),
+			)
+		}
+	case variantText:
+		actions = append(actions,
+			chromedp.SendKeys(".Textinput-Control, input[name=\"rep\"]", solution.Text, chromedp.ByQuery),
+			chromedp.Sleep(time.Second),
+		)
+	case variantSlider:
+		slide, err := slideActions(ctx, solution.Points[0].X*scale/max(rect.Width, 1))
+
+		if err != nil {
+			return solution, err
+		}
+
+		actions = append(actions, slide...)
+	}
+
+	actions = append(actions,
+		chromedp.Evaluate("document.querySelector('.CaptchaButton-ProgressWrapper')?.click()", nil),
+		chromedp.WaitReady("body"),
+		chromedp.Sleep(time.Second),
+	)
+
+	return solution, chromedp.Run(ctx, actions...)
+}
+
+func loadCaptchaTask(page captchaPage) (captcha.Task, error) {
+	if page.Image == "" {
+		return captcha.Task{}, fmt.Errorf("no image of the %v captcha", page.Variant)
+	}
+
+	image, err := captcha.LoadImage(page.Image)
+
+	if err != nil {
+		return captcha.Task{}, err
+	}
+
+	task := captcha.Task{
+		Type:  page.Variant,
+		Image: image,
+	}
+
+	switch page.Variant {
+	case variantSilhouette:
+		task.Type = captcha.TaskSilhouette
+		task.Comment = "Нажмите на фигуры на картинке в порядке, показанном на инструкции"
+		task.Instruction, err = captcha.LoadImage(page.Instruction)
+	case variantText:
+		task.Type = captcha.TaskText
+		task.Comment = "Введите текст с картинки"
+	case variantSlider:
+		task.Type = captcha.TaskSlider
+		task.Comment = "Нажмите на место картинки, где должен стоять фрагмент пазла"
+	}
+
+	return task, err
+}
+
+// slideActions drags the slider button by the share of its track
+func slideActions(ctx context.Context, share float64) ([]chromedp.Action, error) {
+	var track, button geometry.Rectangle
+
+	err := chromedp.Run(ctx,
+		chromedp.EvaluateAsDevTools(fmt.Sprintf(rectJs, ".CaptchaSlider"), &track),
+		chromedp.EvaluateAsDevTools(fmt.Sprintf(rectJs, ".CaptchaSlider-Button"), &button),
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if track.Width == 0 || button.Width == 0 {
+		return nil, errors.New("captcha slider not found")
+	}
+
+	startX := button.Left + button.Width/2
+	y := button.Top + button.Height/2
+	offset := min(max(share, 0), 1) * (track.Width - button.Width)
+	steps := 20
+
+	actions := []chromedp.Action{
+		chromedp.MouseEvent(input.MouseMoved, startX, y),
+		chromedp.MouseEvent(input.MousePressed, startX, y, chromedp.ButtonLeft),
+	}
+
+	// move like a hand does, with small pauses
+	for i := 1; i <= steps; i++ {
+		actions = append(actions,
+			chromedp.MouseEvent(input.MouseMoved, startX+offset*float64(i)/float64(steps), y, chromedp.ButtonLeft),
+			chromedp.Sleep(time.Millisecond*time.Duration(20+i*5)),
+		)
+	}
+
+	actions = append(actions, chromedp.MouseEvent(input.MouseReleased, startX+offset, y, chromedp.ButtonLeft))
+
+	return actions, nil
+}
+
+// saveCaptchaPage saves a screenshot and the html of the page for analysis, returns the file prefix
+func saveCaptchaPage(ctx context.Context) string {
+	var screenshot []byte
+	var html string
+	name := "captcha/unknown/" + time.Now().Format("20060102-150405.000")
+
+	if err := chromedp.Run(ctx, chromedp.FullScreenshot(&screenshot, 90), chromedp.OuterHTML("html", &html)); err != nil {
+		log.Printf("[WARN] Can't capture captcha page: %v", err)
+	}
+
+	if err := storage.WriteFile(name+".jpg", screenshot); err != nil {
+		log.Printf("[WARN] %v", err)
+	}
+
+	if err := storage.WriteFile(name+".html", html); err != nil {
+		log.Printf("[WARN] %v", err)
+	}
+
+	return name
+}
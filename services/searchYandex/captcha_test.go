@@ -0,0 +1,173 @@
+package searchYandex
+
+import (
+	"context"
+	"github.com/chromedp/chromedp"
+	browserCtl "parser/services/browserctl"
+	"parser/services/captcha"
+	"parser/services/captchaMock"
+	"parser/services/config"
+	"parser/services/errorx"
+	"parser/services/geometry"
+	"strings"
+	"testing"
+)
+
+// startBrowser returns a headless chrome context, the test is skipped when chrome is not installed
+func startBrowser(t *testing.T) context.Context {
+	ctx, cancel := browserCtl.GetContext(context.Background(), browserCtl.GetContextOptions{})
+	t.Cleanup(cancel)
+
+	if err := chromedp.Run(ctx); err != nil {
+		t.Skipf("chrome is not available: %v", err)
+	}
+
+	return ctx
+}
+
+// openMockCaptcha selects the solver and loads a search page of the server, which redirects to the captcha
+func openMockCaptcha(t *testing.T, server *captchaMock.Server, solver *captchaMock.Solver) context.Context {
+	captcha.Register(solver)
+	captcha.SetOrder(solver.Name())
+	t.Cleanup(func() { captcha.SetOrder(config.CaptchaSolvers) })
+
+	ctx := startBrowser(t)
+	_, err := LoadPage(ctx, server.SearchUrl("купить телефон"), nil)
+
+	if err == nil || err.Error() != CaptchaError {
+		t.Fatalf("expected captcha, got %v", err)
+	}
+
+	return ctx
+}
+
+func onCaptchaPage(t *testing.T, ctx context.Context) bool {
+	var location string
+
+	if err := chromedp.Run(ctx, chromedp.Location(&location)); err != nil {
+		t.Fatal(err)
+	}
+
+	return strings.Contains(location, "showcaptcha")
+}
+
+func solveMockCaptcha(t *testing.T, server *captchaMock.Server, solver *captchaMock.Solver) int {
+	ctx := openMockCaptcha(t, server, solver)
+	solved, err := SolveCaptcha(ctx)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if onCaptchaPage(t, ctx) {
+		t.Errorf("still on the captcha page")
+	}
+
+	return solved
+}
+
+func TestSolveCaptchaSilhouette(t *testing.T) {
+	points := []geometry.Point{{X: 40, Y: 50}, {X: 150, Y: 120}, {X: 260, Y: 30}}
+	server := captchaMock.NewServer(captchaMock.VariantSilhouette, points, "")
+	defer server.Close()
+
+	solver := captchaMock.NewSolver("mock-silhouette", points, "")
+	solver.NotReady = 1
+
+	if solved := solveMockCaptcha(t, server, solver); solved != 1 {
+		t.Errorf("solved %v captchas, want 1", solved)
+	}
+
+	tasks := solver.Tasks()
+
+	if len(tasks) != 1 || tasks[0].Type != captcha.TaskSilhouette || len(tasks[0].Instruction) == 0 {
+		t.Errorf("unexpected tasks %+v", tasks)
+	}
+
+	if checks, passed := server.Stats(); checks != 1 || passed != 1 {
+		t.Errorf("server checks %v passed %v, want 1 1", checks, passed)
+	}
+}
+
+func TestSolveCaptchaText(t *testing.T) {
+	server := captchaMock.NewServer(captchaMock.VariantText, nil, "окно42")
+	defer server.Close()
+
+	solver := captchaMock.NewSolver("mock-text", nil, "окно42")
+
+	if solved := solveMockCaptcha(t, server, solver); solved != 1 {
+		t.Errorf("solved %v captchas, want 1", solved)
+	}
+
+	if len(solver.Reported()) != 0 {
+		t.Errorf("correct solution reported as wrong")
+	}
+}
+
+func TestSolveCaptchaSlider(t *testing.T) {
+	points := []geometry.Point{{X: 180, Y: 0}}
+	server := captchaMock.NewServer(captchaMock.VariantSlider, points, "")
+	defer server.Close()
+
+	solver := captchaMock.NewSolver("mock-slider", points, "")
+
+	if solved := solveMockCaptcha(t, server, solver); solved != 1 {
+		t.Errorf("solved %v captchas, want 1", solved)
+	}
+
+	if tasks := solver.Tasks(); len(tasks) != 1 || tasks[0].Type != captcha.TaskSlider {
+		t.Errorf("unexpected tasks %+v", tasks)
+	}
+}
+
+// the solver answers after applyTimeout, the wait must not cut the page actions
+func TestSolveCaptchaSlowSolver(t *testing.T) {
+	if testing.Short() {
+		t.Skip("slow solver takes more than 20 seconds")
+	}
+
+	server := captchaMock.NewServer(captchaMock.VariantText, nil, "окно42")
+	defer server.Close()
+
+	solver := captchaMock.NewSolver("mock-slow", nil, "окно42")
+
+	// polls after 2, 5, 9.5, 16.25 and 26.25 seconds
+	solver.NotReady = 4
+
+	if solved := solveMockCaptcha(t, server, solver); solved != 1 {
+		t.Errorf("solved %v captchas, want 1", solved)
+	}
+
+	if checks, passed := server.Stats(); checks != 1 || passed != 1 {
+		t.Errorf("server checks %v passed %v, want 1 1", checks, passed)
+	}
+}
+
+func TestSolveCaptchaWrongSolution(t *testing.T) {
+	server := captchaMock.NewServer(captchaMock.VariantText, nil, "окно42")
+	defer server.Close()
+
+	solver := captchaMock.NewSolver("mock-wrong", nil, "дверь17")
+	ctx := openMockCaptcha(t, server, solver)
+	solved, err := SolveCaptcha(ctx)
+
+	if !errorx.Is(err, errorx.KindCaptcha) {
+		t.Errorf("expected a captcha error, got %v", err)
+	}
+
+	if !onCaptchaPage(t, ctx) {
+		t.Errorf("wrong solution passed the captcha")
+	}
+
+	// the first round clicks the checkbox, every next one submits and reports a wrong solution
+	checks, passed := server.Stats()
+	reported := solver.Reported()
+
+	if solved != config.CaptchaMaxRounds-1 || checks != solved || passed != 0 {
+		t.Errorf("solved %v, server checks %v passed %v, want %v %v 0", solved, checks, passed, config.CaptchaMaxRounds-1, config.CaptchaMaxRounds-1)
+	}
+
+	if len(reported) != solved || reported[0] != "1" {
+		t.Errorf("reported tasks %v, want %v of them", reported, solved)
+	}
+}
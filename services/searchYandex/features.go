@@ -0,0 +1,108 @@
+package searchYandex
+
+import (
+	"github.com/PuerkitoBio/goquery"
+	"net/url"
+	"parser/services/searchEngine"
+	"strings"
+)
+
+// wizards maps data-fast-name / data-fast-wzrd values of serp items to block types
+var wizards = map[string]string{
+	"images":             searchEngine.BlockImages,
+	"images_ya":          searchEngine.BlockImages,
+	"videowiz":           searchEngine.BlockVideo,
+	"video-unisearch":    searchEngine.BlockVideo,
+	"video":              searchEngine.BlockVideo,
+	"news":               searchEngine.BlockNews,
+	"ynews":              searchEngine.BlockNews,
+	"companies":          searchEngine.BlockMaps,
+	"maps":               searchEngine.BlockMaps,
+	"geo":                searchEngine.BlockMaps,
+	"suggest_fact":       searchEngine.BlockFact,
+	"entity-fact":        searchEngine.BlockFact,
+	"fact":               searchEngine.BlockFact,
+	"calculator":         searchEngine.BlockFact,
+	"entity_search":      searchEngine.BlockPeopleAlsoSearch,
+	"related_discovery":  searchEngine.BlockPeopleAlsoSearch,
+	"also_search":        searchEngine.BlockPeopleAlsoSearch,
+	"related":            searchEngine.BlockRelated,
+	"related_queries":    searchEngine.BlockRelated,
+	"search_suggestions": searchEngine.BlockRelated,
+}
+
+const relatedSelector = ".RelatedBottom, .related:not(li)"
+
+// blockEntrySelectors are entries of the block (organizations, videos, queries)
+var blockEntrySelectors = map[string]string{
+	searchEngine.BlockMaps:             ".OrgmnCard, .OrgMn-Item, .Organic",
+	searchEngine.BlockVideo:            ".VideoSnippet, .Video-Item, .VideoHorizontalList-Item",
+	searchEngine.BlockNews:             ".NewsItem, .News-Item, .Story",
+	searchEngine.BlockImages:           ".ImagesList-Item, .Images-Item, .Thumb",
+	searchEngine.BlockPeopleAlsoSearch: ".EntitySearch-Item, .Carousel-Item, .RelatedDiscovery-Item",
+	searchEngine.BlockRelated:          ".Related-Item, .RelatedBottom-Item, .related__item",
+}
+
+// getBlockType returns SERP block type of the serp item or "" for organic result
+func getBlockType(node *goquery.Selection) string {
+	if node.Find(".AdvLabel-Text, .Label_theme_direct").Length() > 0 {
+		return searchEngine.BlockAd
+	}
+
+	for _, attr := range []string{"data-fast-name", "data-fast-wzrd"} {
+		if name, ok := node.Attr(attr); ok {
+			if blockType, ok := wizards[name]; ok {
+				return blockType
+			}
+		}
+	}
+
+	if node.Find(".Fact, .FactAnswer").Length() > 0 {
+		return searchEngine.BlockFact
+	}
+
+	return ""
+}
+
+func parseBlock(node *goquery.Selection, blockType string) searchEngine.SERPBlock {
+	block := searchEngine.SERPBlock{
+		Type:  blockType,
+		Title: cleanText(node.Find(".OrganicTitleContentSpan, .OrganicTitle, h2").First().Text()),
+		Text:  cleanText(node.Find(".OrganicTextContentSpan, .Fact-Answer, .FactAnswer").First().Text()),
+	}
+
+	if linkUrl, ok := node.Find("a.Link, a[href]").First().Attr("href"); ok {
+		if u, err := url.Parse(linkUrl); err == nil {
+			block.URL = u.String()
+			block.Domain = u.Hostname()
+		}
+	}
+
+	entrySelector, ok := blockEntrySelectors[blockType]
+
+	if !ok {
+		return block
+	}
+
+	node.Find(entrySelector).Each(func(i int, entry *goquery.Selection) {
+		item := searchEngine.SERPItem{
+			Pos:   i + 1,
+			Title: cleanText(entry.Text()),
+		}
+
+		if linkUrl, ok := entry.Find("a[href]").First().Attr("href"); ok {
+			if u, err := url.Parse(linkUrl); err == nil {
+				item.URL = u.String()
+				item.Domain = u.Hostname()
+			}
+		}
+
+		block.Items = append(block.Items, item)
+	})
+
+	return block
+}
+
+func cleanText(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
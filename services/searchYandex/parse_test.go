@@ -0,0 +1,105 @@
+package searchYandex
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"parser/services/searchEngine"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// go test ./services/searchYandex -update rewrites golden files after an intended markup change
+var update = flag.Bool("update", false, "update golden files")
+
+type golden struct {
+	Blocked bool                    `json:"blocked"`
+	Page    searchEngine.ParsedPage `json:"page"`
+}
+
+func TestParsePageGolden(t *testing.T) {
+	fixtures, err := filepath.Glob("testdata/*.html")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, fixture := range fixtures {
+		name := strings.TrimSuffix(filepath.Base(fixture), ".html")
+
+		t.Run(name, func(t *testing.T) {
+			html := readFixture(t, filepath.Base(fixture))
+			actual, err := json.MarshalIndent(golden{
+				Blocked: IsBlocked("", html),
+				Page:    ParsePage(html),
+			}, "", "  ")
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			goldenPath := "testdata/" + name + ".golden.json"
+
+			if *update {
+				if err := os.WriteFile(goldenPath, append(actual, '\n'), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			expected, err := os.ReadFile(goldenPath)
+
+			if err != nil {
+				t.Fatalf("no golden file (run with -update): %v", err)
+			}
+
+			if !bytes.Equal(bytes.TrimSpace(expected), bytes.TrimSpace(actual)) {
+				t.Errorf("parsed page differs from %v\nexpected:\n%s\nactual:\n%s", goldenPath, expected, actual)
+			}
+		})
+	}
+}
+
+func TestIsBlocked(t *testing.T) {
+	cases := []struct {
+		name     string
+		finalUrl string
+		fixture  string
+		expected bool
+	}{
+		{"captcha redirect", "https://yandex.ru/showcaptcha?cc=1&retpath=https%3A%2F%2Fyandex.ru%2Fsearch", "captcha.html", true},
+		{"captcha without redirect", "https://yandex.ru/search/?text=spoofing", "captcha.html", true},
+		{"organic", "https://yandex.ru/search/?text=spoofing", "organic_page0.html", false},
+		{"empty", "https://yandex.ru/search/?text=qwzxqwzxqwzx", "empty.html", false},
+		{"wizards", "https://yandex.ru/search/?text=pizza", "wizards.html", false},
+		// full-size pages, the SERP of a query about captchas has the markers as text
+		{"full captcha page", "https://yandex.ru/search/?text=spoofing", "captcha_full.html", true},
+		{"full serp about captchas", "https://yandex.ru/search/?text=checkcaptcha", "serp_full.html", false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if actual := IsBlocked(c.finalUrl, readFixture(t, c.fixture)); actual != c.expected {
+				t.Errorf("expected %v, got %v", c.expected, actual)
+			}
+		})
+	}
+}
+
+func TestParsePageNotEmpty(t *testing.T) {
+	// pages with results must never parse into empty titles or urls (silent data loss)
+	for _, fixture := range []string{"organic_page0.html", "organic_page1.html", "wizards.html", "mobile.html"} {
+		parsedPage := ParsePage(readFixture(t, fixture))
+
+		if len(parsedPage.Items) == 0 {
+			t.Errorf("%v: no organic items", fixture)
+		}
+
+		for _, item := range parsedPage.Items {
+			if item.Title == "" || item.URL == "" {
+				t.Errorf("%v: item %v has empty title or url", fixture, item.Pos)
+			}
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package searchYandex
+
+import (
+	"os"
+	"parser/services/searchEngine"
+	"testing"
+)
+
+func readFixture(t *testing.T, name string) string {
+	t.Helper()
+
+	data, err := os.ReadFile("testdata/" + name)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(data)
+}
+
+func TestPositionsAcrossPages(t *testing.T) {
+	offset := searchEngine.Position{}
+	items := []searchEngine.SERPItem{}
+	blocks := []searchEngine.SERPBlock{}
+
+	for _, name := range []string{"organic_page0.html", "organic_page1.html"} {
+		parsedPage := ParsePage(readFixture(t, name))
+		offset = parsedPage.Shift(offset)
+		items = append(items, parsedPage.Items...)
+		blocks = append(blocks, parsedPage.Blocks...)
+	}
+
+	expectedItems := []struct {
+		domain string
+		pos    int
+		absPos int
+	}{
+		{"ru.wikipedia.org", 1, 2},
+		{"www.kaspersky.ru", 2, 3},
+		{"habr.com", 3, 5},
+		{"www.securitylab.ru", 4, 6},
+		{"www.cloudflare.com", 5, 8},
+		{"encyclopedia.kaspersky.ru", 6, 9},
+	}
+
+	if len(items) != len(expectedItems) {
+		t.Fatalf("expected %v organic items, got %v", len(expectedItems), len(items))
+	}
+
+	for i, expected := range expectedItems {
+		item := items[i]
+
+		if item.Domain != expected.domain || item.Pos != expected.pos || item.AbsPos != expected.absPos {
+			t.Errorf("item %v: expected %v pos=%v abs_pos=%v, got %v pos=%v abs_pos=%v",
+				i, expected.domain, expected.pos, expected.absPos, item.Domain, item.Pos, item.AbsPos)
+		}
+	}
+
+	expectedBlocks := []struct {
+		blockType string
+		pos       int
+		placement string
+	}{
+		{searchEngine.BlockAd, 1, "top"},
+		{searchEngine.BlockImages, 4, ""},
+		{searchEngine.BlockAd, 7, "bottom"},
+	}
+
+	if len(blocks) != len(expectedBlocks) {
+		t.Fatalf("expected %v blocks, got %v", len(expectedBlocks), len(blocks))
+	}
+
+	for i, expected := range expectedBlocks {
+		block := blocks[i]
+
+		if block.Type != expected.blockType || block.Pos != expected.pos || block.Placement != expected.placement {
+			t.Errorf("block %v: expected %v pos=%v placement=%v, got %v pos=%v placement=%v",
+				i, expected.blockType, expected.pos, expected.placement, block.Type, block.Pos, block.Placement)
+		}
+	}
+
+	if offset.Organic != 6 || offset.Absolute != 9 {
+		t.Errorf("unexpected running offset %+v", offset)
+	}
+}
+
+func TestPositionsDoNotDependOnPageLength(t *testing.T) {
+	// page 1 has fewer nodes than page 0, positions must continue from page 0
+	parsedPage := ParsePage(readFixture(t, "organic_page1.html"))
+	parsedPage.Shift(searchEngine.Position{Organic: 4, Absolute: 7})
+
+	if parsedPage.Items[0].Pos != 5 || parsedPage.Items[1].Pos != 6 {
+		t.Errorf("expected positions 5, 6 got %v, %v", parsedPage.Items[0].Pos, parsedPage.Items[1].Pos)
+	}
+}
@@ -10,37 +10,26 @@ import (
 	"math/rand"
 	"net/url"
 	browserCtl "parser/services/browserctl"
-	"parser/services/config"
-	"parser/services/httpRequest"
-	"parser/services/proxyx"
+	"parser/services/searchEngine"
+	"parser/services/selectors"
 	"strconv"
 	"strings"
 	"time"
 )
 
-// \SearchPhrase Engine Results Page Item
-type SERPItem struct {
-	Pos    int    `json:"pos"`
-	URL    string `json:"url"`
-	Domain string `json:"domain"`
-	Title  string `json:"title"`
-	Text   string `json:"text"`
-}
-
-type Stats struct {
-	TotalPages         int    `json:"total_pages_loaded"`
-	TotalCaptchaSolved int    `json:"total_captcha_solved"`
-	AccessSuspended    int    `json:"access_suspended"`
-	LoadingErrors      int    `json:"loading_errors"`
-	TimeSpend          string `json:"time_spent"`
-}
+const CaptchaError string = "Captcha error"
 
-type TResult struct {
-	Items []SERPItem
-	Stats Stats
+// captchaMarkers are page fragments of the captcha page (served without redirect too).
+// A SERP of a query about captchas contains them as text, so a page with a marker is
+// checked against captchaSelector.
+var captchaMarkers = []string{
+	"/checkcaptcha",
+	"CheckboxCaptcha",
+	"AdvancedCaptcha",
 }
 
-const CaptchaError string = "Captcha error"
+// captchaSelector are elements of the captcha page markup
+const captchaSelector = "form[action^='/checkcaptcha'], [class*='CheckboxCaptcha'], [class*='AdvancedCaptcha']"
 
 func generateMSID() string {
 	timestamp := time.Now().UnixNano()
@@ -89,7 +78,7 @@ func GetSearchPageUrl(text string, lr string, page int) string {
 //	}
 //
 // fmt.Println("Содержимое страницы:", content)
-func LoadPage(ctx context.Context, url string, session *Session) (string, error) {
+func LoadPage(ctx context.Context, url string, session *searchEngine.Session) (string, error) {
 	var locationHref string
 	var html string
 	var err error
@@ -126,6 +115,23 @@ func LoadPage(ctx context.Context, url string, session *Session) (string, error)
 	return html, nil
 }
 
+// IsBlocked определяет страницу капчи по адресу (редирект на showcaptcha) или по содержимому
+func IsBlocked(finalUrl string, html string) bool {
+	if strings.Contains(finalUrl, "showcaptcha") {
+		return true
+	}
+
+	for _, marker := range captchaMarkers {
+		if strings.Contains(html, marker) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+
+			return err == nil && doc.Find(captchaSelector).Length() > 0
+		}
+	}
+
+	return false
+}
+
 func sleep(min, max float64) {
 	if min > max {
 		log.Fatalf("invalid arguments: min (%f) > max (%f)", min, max)
@@ -144,187 +150,82 @@ func sleep(min, max float64) {
 	time.Sleep(time.Duration(duration * float64(time.Second)))
 }
 
+// GetHeaders returns the navigation headers, User-Agent, client hints and Accept-Language
+// are added from the session fingerprint profile
 func GetHeaders() map[string]string {
 	return map[string]string{
 		"Accept":                    "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8,application/signed-exchange;v=b3;q=0.7",
 		"Accept-Encoding":           "gzip, deflate, br, zstd",
 		"Accept-Language":           "ru-RU,ru;q=0.9,en-US;q=0.8,en;q=0.7",
 		"Cache-Control":             "no-cache",
-		"Connection":                "keep-alive",
-		"Host":                      "dzen.ru",
 		"Pragma":                    "no-cache",
 		"Sec-Fetch-Dest":            "document",
 		"Sec-Fetch-Mode":            "navigate",
-		"Sec-Fetch-Site":            "none",
+		"Sec-Fetch-Site":            "same-origin",
 		"Sec-Fetch-User":            "?1",
 		"Upgrade-Insecure-Requests": "1",
-		"User-Agent":                "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/125.0.0.0 Safari/537.36",
-		"sec-ch-ua":                 "\"Not)A;Brand\";v=\"8\", \"Chromium\";v=\"138\", \"Google Chrome\";v=\"138\"",
-		"sec-ch-ua-mobile":          "?0",
-		"sec-ch-ua-platform":        "Windows",
 		"Referer":                   "https://ya.ru/",
 	}
 }
 
-func ParsePage(html string, page int) []SERPItem {
+// ParsePage разбирает страницу выдачи: органические результаты и блоки
+// (реклама, колдунщики, связанные запросы) с их позицией на странице.
+// Pos - место среди органических результатов, AbsPos - среди всех блоков страницы.
+func ParsePage(html string) searchEngine.ParsedPage {
 	doc, _ := goquery.NewDocumentFromReader(strings.NewReader(html))
 
-	result := []SERPItem{}
-	nodes := doc.Find("li.serp-item:not(:has(.AdvLabel-Text)):not([data-fast-name=\"images\"])")
-	nodes.Each(func(i int, node *goquery.Selection) {
-		aNode := node.Find("a.Link")
-		titleEl := node.Find(".OrganicTitleContentSpan")
-		textEl := node.Find(".OrganicTextContentSpan")
-		linkUrl, _ := aNode.Attr("href")
-		u, _ := url.Parse(linkUrl)
-
-		result = append(result, SERPItem{
-			Pos:    i + 1 + page*nodes.Length(),
-			URL:    u.String(),
-			Domain: u.Hostname(),
-			Title:  titleEl.Text(),
-			Text:   textEl.Text(),
-		})
-	})
-
-	return result
-}
-
-func tryGenerateSession(text string, lr string, oldSession *Session) (Session, int, *proxyx.TProxy, error) {
-	var session Session
-	var solvedCaptcha int
-	var err error
-	var proxy *proxyx.TProxy
-
-	if config.UseProxy {
-		proxyStruct := proxyx.GetProxy()
-		proxy = &proxyStruct
-	}
-
-	for i := 1; i <= config.AttemptsToGenerateSession; i++ {
-		session, solvedCaptcha, err = GenerateSession(text, lr, proxy, oldSession)
-
-		if err != nil {
-			log.Printf("[WARN] " + err.Error())
-
-			//try another proxy
-			if config.UseProxy {
-				proxyStruct := proxyx.GetProxy()
-				proxy = &proxyStruct
-			}
-
-			continue
-		}
-
-		return session, solvedCaptcha, proxy, nil
+	result := searchEngine.ParsedPage{
+		Items:           []searchEngine.SERPItem{},
+		Blocks:          []searchEngine.SERPBlock{},
+		SelectorVersion: Selectors.Version,
+		Selectors:       selectors.Matches{},
 	}
+	nodes := Selectors.FindAll(doc, "item", result.Selectors)
 
-	return session, solvedCaptcha, proxy, err
-}
-
-func ParseKeywordsList(keywords []string, lr string) ([]SERPItem, Stats) {
-	var session Session
-	var solvedCaptcha int
-	var err error
-	var proxy *proxyx.TProxy = nil
-
-	result := []SERPItem{}
-	solvedCaptchaTotal := 0
-	session, solvedCaptcha, proxy, err = tryGenerateSession(keywords[0], lr, nil)
-
-	if err != nil {
-		panic("Can't generate session: " + err.Error())
-	}
-
-	solvedCaptchaTotal += solvedCaptcha
-	accessSuspended := 0
-	loadingErrors := 0
-	startTime := time.Now()
-	totalPages := 0
-
-	for j := 0; j < len(keywords); j++ {
-		parsed := []SERPItem{}
-		for page := 0; page < config.Deep; page++ {
-
-			keyword := keywords[j]
-			url := GetSearchPageUrl(keyword, lr, page)
-			log.Printf("[INFO] Parse KW: `%v[%v]`", keyword, page)
-			headers := GetHeaders()
-			headers["Cookie"] = CookieToString(session.Cookie)
-			options := map[string]map[string]string{
-				"headers": headers,
-			}
-
-			if config.UseProxy {
-				options["proxy"] = map[string]string{
-					"proxyStr": proxyx.StructToStr(*proxy),
-				}
-			}
-
-			html, resp, _ := httpRequest.GetCycleTls(url, &options)
-
-			sessionInterrupted := false
-
-			if strings.Contains(resp.FinalUrl, "showcaptcha") {
-				sessionInterrupted = true
-			}
-
-			if resp.Status >= 400 {
-				log.Printf("[WARN] Page Load error (status: %v)", resp.Status)
-				loadingErrors += 1
-				sessionInterrupted = true
-			}
-
-			//generate new session
-			if sessionInterrupted {
-				page -= 1
+	nodes.Each(func(i int, node *goquery.Selection) {
+		blockType := getBlockType(node)
 
-				if config.UseProxy {
-					proxyStruct := proxyx.GetProxy()
-					proxy = &proxyStruct
-				}
+		if blockType != "" {
+			block := parseBlock(node, blockType)
+			block.Pos = i + 1
 
-				session, solvedCaptcha, proxy, err = tryGenerateSession(keyword, lr, &session)
+			if blockType == searchEngine.BlockAd {
+				block.Placement = "top"
 
-				if err != nil {
-					panic("Can't generate session: " + err.Error())
+				if len(result.Items) > 0 {
+					block.Placement = "bottom"
 				}
-
-				solvedCaptchaTotal += solvedCaptcha
-				accessSuspended += 1
-				continue
 			}
 
-			log.Printf("[INFO] Parsed")
-			parsed = append(parsed, ParsePage(html, page)...)
-			totalPages += 1
+			result.Blocks = append(result.Blocks, block)
+			return
 		}
 
-		result = append(result, parsed...)
-	}
-
-	elapsed := time.Since(startTime)
-	hours := int(elapsed.Hours())
-	minutes := int(elapsed.Minutes()) % 60
-	seconds := int(elapsed.Seconds()) % 60
-	stats := Stats{
-		TotalPages:         totalPages,
-		TotalCaptchaSolved: solvedCaptchaTotal,
-		TimeSpend:          fmt.Sprintf("%02d:%02d:%02d", hours, minutes, seconds),
-		AccessSuspended:    accessSuspended,
-		LoadingErrors:      loadingErrors,
-	}
+		aNode := Selectors.Find(node, "url", result.Selectors).First()
+		titleEl := Selectors.Find(node, "title", result.Selectors).First()
+		textEl := Selectors.Find(node, "text", result.Selectors).First()
+		linkUrl, _ := aNode.Attr("href")
+		u, _ := url.Parse(linkUrl)
 
-	return result, stats
-}
+		result.Items = append(result.Items, searchEngine.SERPItem{
+			Pos:    len(result.Items) + 1,
+			AbsPos: i + 1,
+			URL:    u.String(),
+			Domain: u.Hostname(),
+			Title:  titleEl.Text(),
+			Text:   textEl.Text(),
+		})
+	})
 
-func ParseKeywordsListRoutine(keywords []string, lr string, channel chan TResult) {
-	items, stats := ParseKeywordsList(keywords, lr)
+	// related queries are rendered below the results list
+	related := doc.Find(relatedSelector)
+	related.Each(func(i int, node *goquery.Selection) {
+		block := parseBlock(node, searchEngine.BlockRelated)
+		block.Pos = nodes.Length() + i + 1
+		result.Blocks = append(result.Blocks, block)
+	})
 
-	result := TResult{
-		Items: items,
-		Stats: stats,
-	}
+	result.Length = nodes.Length() + related.Length()
 
-	channel <- result
+	return result
 }
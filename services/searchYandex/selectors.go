@@ -0,0 +1,34 @@
+package searchYandex
+
+import "parser/services/selectors"
+
+// Selectors is the registry of yandex SERP fields. Bump Version when the chains change,
+// the first selector of a chain is the current markup, the rest are fallbacks.
+var Selectors = selectors.Registry{
+	Version: "2025-07",
+	Fields: map[string][]string{
+		"item": {
+			"li.serp-item",
+			"#search-result > li",
+			"[data-cid]",
+		},
+		"url": {
+			"a.OrganicTitle-Link",
+			"a.Link",
+			".organic__url",
+			"h2 a",
+		},
+		"title": {
+			".OrganicTitleContentSpan",
+			".OrganicTitle-LinkText",
+			".organic__title",
+			"h2",
+		},
+		"text": {
+			".OrganicTextContentSpan",
+			".OrganicText",
+			".organic__text",
+			".TextContainer",
+		},
+	},
+}
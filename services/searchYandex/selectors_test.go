@@ -0,0 +1,54 @@
+package searchYandex
+
+import (
+	"parser/services/searchEngine"
+	"parser/services/selectors"
+	"testing"
+)
+
+func TestMarkupDriftAlert(t *testing.T) {
+	markup := searchEngine.MarkupStats{}
+	markup.Check(ParsePage(readFixture(t, "organic_page0.html")), "organic_page0.html")
+
+	if markup.Status != searchEngine.MarkupOk {
+		t.Fatalf("expected status %v, got %v (%v)", searchEngine.MarkupOk, markup.Status, markup.Alerts)
+	}
+
+	markup.Check(ParsePage(readFixture(t, "drift.html")), "drift.html")
+
+	if markup.Status != searchEngine.MarkupDrift || markup.SuspiciousPages != 1 || len(markup.Alerts) != 1 {
+		t.Errorf("expected drift alert, got %+v", markup)
+	}
+
+	if markup.Selectors["title"][selectors.NotMatched] != 2 {
+		t.Errorf("expected 2 unmatched titles, got %v", markup.Selectors["title"])
+	}
+}
+
+func TestSelectorFallback(t *testing.T) {
+	parsedPage := ParsePage(readFixture(t, "organic_page1.html"))
+
+	if parsedPage.SelectorVersion != Selectors.Version {
+		t.Errorf("expected selector version %v, got %v", Selectors.Version, parsedPage.SelectorVersion)
+	}
+
+	for _, field := range []string{"item", "url", "title", "text"} {
+		primary := Selectors.Fields[field][0]
+
+		if parsedPage.Selectors[field][primary] == 0 {
+			t.Errorf("%v: primary selector `%v` did not match: %v", field, primary, parsedPage.Selectors[field])
+		}
+	}
+
+	// markup without primary title selector falls back to the heading
+	html := `<ul><li class="serp-item"><a class="Link" href="https://example.com/"><h2>Example</h2></a></li></ul>`
+	parsedPage = ParsePage(html)
+
+	if len(parsedPage.Items) != 1 || parsedPage.Items[0].Title != "Example" {
+		t.Fatalf("expected fallback title, got %+v", parsedPage.Items)
+	}
+
+	if parsedPage.Selectors["title"]["h2"] != 1 {
+		t.Errorf("expected fallback selector h2 counted, got %v", parsedPage.Selectors["title"])
+	}
+}
@@ -2,24 +2,29 @@ package searchYandex
 
 import (
 	"context"
-	"github.com/chromedp/cdproto/network"
-	"github.com/chromedp/cdproto/storage"
-	"github.com/chromedp/chromedp"
 	"log"
 	browserCtl "parser/services/browserctl"
-	"parser/services/capsola"
-	"parser/services/geometry"
 	"parser/services/proxyx"
-	"strings"
-	"time"
+	"parser/services/searchEngine"
+	"parser/services/warmup"
 )
 
-type Session struct {
-	Cookie []*network.Cookie
+var warmupTarget = warmup.Target{
+	MainUrl:    "https://yandex.ru/",
+	SearchUrl:  "https://yandex.ru/search/",
+	SuggestUrl: "https://suggest.yandex.ru/suggest-ya.cgi?part=%s",
+	Resources: []string{
+		"https://yastatic.net/s3/web4lib/_/La6qi18Z8LwgnZdsAr1qy2E.woff2",
+		"https://mc.yandex.ru/metrika/tag.js",
+	},
 }
 
-func GenerateSession(text string, lr string, proxy *proxyx.TProxy, oldSession *Session) (Session, int, error) {
-	var proxyStr = proxyx.StructToStr(*proxy)
+func GenerateSession(text string, lr string, proxy *proxyx.TProxy, oldSession *searchEngine.Session) (searchEngine.Session, int, error) {
+	var proxyStr = ""
+
+	if proxy != nil {
+		proxyStr = proxyx.StructToStr(*proxy)
+	}
 
 	if oldSession != nil {
 		log.Printf("[INFO] Retrust session (proxy=%v)", proxyStr)
@@ -27,152 +32,43 @@ func GenerateSession(text string, lr string, proxy *proxyx.TProxy, oldSession *S
 		log.Printf("[INFO] Generate new session (proxy=%v)", proxyStr)
 	}
 
+	session := searchEngine.NewSession(proxy, oldSession)
 	contextOptions := browserCtl.GetContextOptions{
-		Proxy: nil,
-	}
-
-	if proxy != nil {
-		contextOptions.Proxy = proxy
+		Proxy:       proxy,
+		Fingerprint: &session.Fingerprint,
 	}
 
 	ctx, cancelAll := browserCtl.GetContext(context.Background(), contextOptions)
 	defer cancelAll()
 
-	_, err := LoadPage(ctx, GetSearchPageUrl(text, lr, 0), oldSession)
+	// a retrusted session has the cookies already, a new one is warmed up first
+	var warmedUp []string
 
-	var solvedCaptcha = 0
-
-	if err != nil {
-		if err.Error() == CaptchaError {
-			solvedCaptcha = SolveCaptcha(ctx)
-		} else {
-			return Session{}, 0, err
-		}
-	}
-
-	session := Session{
-		Cookie: getCookieFromCtx(ctx),
+	if oldSession == nil {
+		warmedUp = warmup.Run(ctx, warmupTarget, text)
 	}
 
-	return session, solvedCaptcha, nil
-}
-
-func SolveCaptcha(ctx context.Context) int {
-	var isCaptchaSolved = false
-	var solvedCaptchaCount = 0
-
-	for !isCaptchaSolved {
-		log.Printf("[INFO] Captcha offered")
-
-		var res interface{}
-		var clickImageUrl string
-		var taskImageUrl string
-		var currentURL string
-
-		chromedp.Run(ctx,
-			// click button "i am not a robot"
-			chromedp.Evaluate("document.getElementById('js-button')?.click()", &res),
-			chromedp.Sleep(time.Second*2),
-			chromedp.Location(&currentURL),
-		)
-
-		//captcha passed in one click
-		if !strings.Contains(currentURL, "showcaptcha") {
-			log.Printf("[INFO] Captcha checkbox accepted")
-			break
-		}
-
-		log.Printf("[INFO] Solve smart captcha")
+	_, err := LoadPage(ctx, GetSearchPageUrl(text, lr, 0), oldSession)
 
-		timeoutCtx, cancel := context.WithTimeout(ctx, time.Second*20)
-		defer cancel()
+	if oldSession == nil {
+		warmup.RecordOutcome(warmedUp, err != nil && err.Error() == CaptchaError)
+	}
 
-		err := chromedp.Run(timeoutCtx,
-			chromedp.WaitVisible(".AdvancedCaptcha-ImageWrapper img"),
-			chromedp.WaitVisible(".AdvancedCaptcha-SilhouetteTask img"),
-			chromedp.Evaluate("document.querySelector('.AdvancedCaptcha-ImageWrapper img').src", &clickImageUrl),
-			chromedp.Evaluate("document.querySelector('.AdvancedCaptcha-SilhouetteTask img').src", &taskImageUrl),
-		)
+	var solvedCaptcha = 0
 
-		if err == context.DeadlineExceeded {
-			log.Printf("[WARN] Captcha solve deadline exceeded")
-			return 0
+	if err != nil {
+		if err.Error() != CaptchaError {
+			return searchEngine.Session{}, 0, err
 		}
 
-		//[start] get solution Smart Captcha
-		task_id := capsola.SmartCaptchaCreateTask(clickImageUrl, taskImageUrl)
-
-		time.Sleep(time.Second * 1)
-
-		solution_coords := capsola.SmartCaptchaGetSolution(task_id)
-		//[end]
+		solvedCaptcha, err = SolveCaptcha(ctx)
 
-		//[start] solve Smart Captcha
-		var rect geometry.Rectangle
-
-		chromedp.Run(ctx,
-			chromedp.EvaluateAsDevTools(`
-					(function(){
-						var el = document.querySelector('.AdvancedCaptcha-ImageWrapper img');
-						var r = el.getBoundingClientRect();
-						return {left: r.left, top: r.top, width: r.width, height: r.height};
-					})()
-				`, &rect),
-		)
-
-		// modify relative coords to absolute coords
-		for i := 0; i < len(solution_coords); i++ {
-			solution_coords[i].X += rect.Left
-			solution_coords[i].Y += rect.Top
+		if err != nil {
+			return searchEngine.Session{}, solvedCaptcha, err
 		}
-
-		chromedp.Run(ctx,
-			chromedp.MouseClickXY(solution_coords[0].X, solution_coords[0].Y),
-			chromedp.Sleep(time.Second),
-			chromedp.MouseClickXY(solution_coords[1].X, solution_coords[1].Y),
-			chromedp.Sleep(time.Second),
-			chromedp.MouseClickXY(solution_coords[2].X, solution_coords[2].Y),
-			chromedp.Sleep(time.Second),
-			chromedp.MouseClickXY(solution_coords[3].X, solution_coords[3].Y),
-			chromedp.Sleep(time.Second),
-			chromedp.Evaluate("document.querySelector('.CaptchaButton-ProgressWrapper').click()", &res),
-			chromedp.WaitReady("body"),
-			chromedp.Sleep(time.Second),
-			chromedp.Location(&currentURL),
-		)
-		//[end]
-
-		if !strings.Contains(currentURL, "showcaptcha") {
-			isCaptchaSolved = true
-		}
-
-		solvedCaptchaCount++
 	}
 
-	return solvedCaptchaCount
-}
-
-func CookieToString(cookie []*network.Cookie) string {
-	var cookiePairs []string
+	session.Cookie = browserCtl.GetCookies(ctx)
 
-	for _, c := range cookie {
-		cookiePairs = append(cookiePairs, c.Name+"="+c.Value)
-	}
-
-	return strings.Join(cookiePairs, "; ")
-}
-
-func getCookieFromCtx(ctx context.Context) []*network.Cookie {
-	var cookies []*network.Cookie
-
-	chromedp.Run(
-		ctx,
-		chromedp.ActionFunc(func(ctx context.Context) error {
-			var err error
-			cookies, err = storage.GetCookies().Do(ctx)
-			return err
-		}),
-	)
-
-	return cookies
+	return session, solvedCaptcha, nil
 }
@@ -0,0 +1,292 @@
+package searchYandex
+
+import (
+	"encoding/xml"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"parser/services/config"
+	"parser/services/errorx"
+	"parser/services/httpRequest"
+	"parser/services/proxyx"
+	"parser/services/searchEngine"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const xmlPageSize = 10
+
+// api error code of a query without results, the only error that is a valid empty page
+const xmlNotFound = 15
+
+// api error codes meaning the hourly limit is exhausted
+var xmlLimitErrors = []int{32, 55}
+
+// api error codes of a bad account setup: unknown user, ip not registered, invalid key
+var xmlAccessErrors = []int{31, 33, 42, 43}
+
+type xmlResponse struct {
+	Error   *xmlError  `xml:"response>error"`
+	Groups  []xmlGroup `xml:"response>results>grouping>group"`
+	Limited bool       `xml:"-"`
+}
+
+type xmlError struct {
+	Code    int    `xml:"code,attr"`
+	Message string `xml:",chardata"`
+}
+
+type xmlGroup struct {
+	Docs []xmlDoc `xml:"doc"`
+}
+
+type xmlDoc struct {
+	URL      string    `xml:"url"`
+	Domain   string    `xml:"domain"`
+	Title    xmlText   `xml:"title"`
+	Passages []xmlText `xml:"passages>passage"`
+}
+
+// xmlText collects text of an element with <hlword> highlights inside
+type xmlText string
+
+func (t *xmlText) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var text strings.Builder
+
+	for {
+		token, err := d.Token()
+
+		if err != nil {
+			return err
+		}
+
+		switch v := token.(type) {
+		case xml.CharData:
+			text.Write(v)
+		case xml.EndElement:
+			if v.Name == start.Name {
+				*t = xmlText(strings.TrimSpace(text.String()))
+				return nil
+			}
+		}
+	}
+}
+
+// hourlyLimiter keeps the number of API requests within config.YandexXmlHourlyLimit
+type hourlyLimiter struct {
+	mu             sync.Mutex
+	hour           time.Time
+	used           int
+	limit          int       // 0 - no limit
+	exhaustedUntil time.Time // the api answered with a limit error
+}
+
+var xmlLimiter = &hourlyLimiter{limit: config.YandexXmlHourlyLimit}
+
+// Wait blocks until a request is allowed in the current hour
+func (l *hourlyLimiter) Wait() {
+	for {
+		wait := l.reserve(time.Now())
+
+		if wait <= 0 {
+			return
+		}
+
+		log.Printf("[INFO] Yandex XML hourly limit reached, waiting %v", wait.Round(time.Second))
+		time.Sleep(wait)
+	}
+}
+
+// reserve counts a request and returns 0 when it is allowed, otherwise the time to wait
+func (l *hourlyLimiter) reserve(now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Before(l.exhaustedUntil) {
+		return l.exhaustedUntil.Sub(now)
+	}
+
+	hour := now.Truncate(time.Hour)
+
+	if !hour.Equal(l.hour) {
+		l.hour = hour
+		l.used = 0
+	}
+
+	if l.limit <= 0 || l.used < l.limit {
+		l.used++
+		return 0
+	}
+
+	return hour.Add(time.Hour).Sub(now)
+}
+
+// Exhaust pauses requests till the next hour (api answered with a limit error)
+func (l *hourlyLimiter) Exhaust() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.exhaustedUntil = time.Now().Truncate(time.Hour).Add(time.Hour)
+}
+
+// GetXmlPageUrl формирует URL запроса к Yandex Search API (XML).
+// Учётные данные берутся из переменных окружения YANDEX_XML_USER и YANDEX_XML_KEY.
+func GetXmlPageUrl(text string, lr string, page int) string {
+	pageUrl, _ := url.Parse(config.YandexXmlUrl)
+	params := url.Values{}
+	params.Add("user", os.Getenv("YANDEX_XML_USER"))
+	params.Add("key", os.Getenv("YANDEX_XML_KEY"))
+	params.Add("query", text)
+	params.Add("lr", lr)
+	params.Add("l10n", "ru")
+	params.Add("sortby", "rlv")
+	params.Add("filter", "none")
+	params.Add("groupby", fmt.Sprintf("attr=d.mode=deep.groups-on-page=%d.docs-in-group=1", xmlPageSize))
+
+	if page > 0 {
+		params.Add("page", strconv.Itoa(page))
+	}
+
+	pageUrl.RawQuery = params.Encode()
+
+	return pageUrl.String()
+}
+
+func parseXml(body string) (xmlResponse, error) {
+	var data xmlResponse
+
+	if err := xml.Unmarshal([]byte(body), &data); err != nil {
+		return data, err
+	}
+
+	if data.Error != nil && slices.Contains(xmlLimitErrors, data.Error.Code) {
+		data.Limited = true
+	}
+
+	return data, nil
+}
+
+// ParseXmlPage maps <group><doc> results of the api response to SERP items
+func ParseXmlPage(body string) []searchEngine.SERPItem {
+	result := []searchEngine.SERPItem{}
+	data, err := parseXml(body)
+
+	if err != nil {
+		log.Printf("[WARN] Yandex XML parse error: %v", err)
+		return result
+	}
+
+	for _, group := range data.Groups {
+		for _, doc := range group.Docs {
+			passages := []string{}
+
+			for _, passage := range doc.Passages {
+				passages = append(passages, string(passage))
+			}
+
+			u, _ := url.Parse(doc.URL)
+			domain := doc.Domain
+
+			if domain == "" {
+				domain = u.Hostname()
+			}
+
+			result = append(result, searchEngine.SERPItem{
+				Pos:    len(result) + 1,
+				AbsPos: len(result) + 1,
+				URL:    u.String(),
+				Domain: domain,
+				Title:  string(doc.Title),
+				Text:   strings.Join(passages, " "),
+			})
+		}
+	}
+
+	return result
+}
+
+// XmlEngine is the official Yandex Search API (XML) fetch mode
+type XmlEngine struct{}
+
+func init() {
+	searchEngine.Register(XmlEngine{})
+}
+
+func (XmlEngine) Name() string {
+	return "yandex-xml"
+}
+
+func (XmlEngine) GetSearchPageUrl(text string, lr string, page int) string {
+	return GetXmlPageUrl(text, lr, page)
+}
+
+// Direct is true: the account is bound to the server ip, so proxy is not used
+func (XmlEngine) Direct() bool {
+	return true
+}
+
+// Fetch requests the api directly (see Direct). Api errors except the empty result and the
+// hourly limit are returned as typed errors.
+func (XmlEngine) Fetch(pageUrl string, session *searchEngine.Session, proxy *proxyx.TProxy) (searchEngine.TResponse, error) {
+	xmlLimiter.Wait()
+
+	body, resp, err := httpRequest.Get(pageUrl, map[string]map[string]string{})
+
+	if err != nil {
+		return searchEngine.TResponse{}, err
+	}
+
+	result := searchEngine.TResponse{
+		Html:     body,
+		Status:   resp.StatusCode,
+		FinalUrl: resp.Request.URL.String(),
+	}
+
+	data, err := parseXml(body)
+
+	if err != nil || data.Error == nil || data.Error.Code == xmlNotFound {
+		return result, nil
+	}
+
+	code, message := data.Error.Code, strings.TrimSpace(data.Error.Message)
+	log.Printf("[WARN] Yandex XML error %v: %v", code, message)
+
+	// the limit is a blocked page (see IsBlocked), other errors fail the page instead of
+	// being parsed as a page without results
+	if data.Limited {
+		xmlLimiter.Exhaust()
+		return result, nil
+	}
+
+	kind := errorx.KindNetwork
+
+	if slices.Contains(xmlAccessErrors, code) {
+		kind = errorx.KindBan
+	}
+
+	return result, errorx.Errorf(kind, "yandex xml", "error %v: %v", code, message)
+}
+
+func (XmlEngine) IsBlocked(resp searchEngine.TResponse) bool {
+	data, err := parseXml(resp.Html)
+
+	return err == nil && data.Limited
+}
+
+func (XmlEngine) ParsePage(html string) searchEngine.ParsedPage {
+	items := ParseXmlPage(html)
+
+	return searchEngine.ParsedPage{
+		Items:  items,
+		Length: len(items),
+	}
+}
+
+// GenerateSession is a no-op: the api is authorized by user/key
+func (XmlEngine) GenerateSession(text string, lr string, proxy *proxyx.TProxy, oldSession *searchEngine.Session) (searchEngine.Session, int, error) {
+	return searchEngine.Session{}, 0, nil
+}
@@ -0,0 +1,74 @@
+package searchYandex
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"parser/services/errorx"
+	"testing"
+	"time"
+)
+
+func TestHourlyLimiter(t *testing.T) {
+	hour := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)
+	limiter := &hourlyLimiter{limit: 2}
+
+	for i, expected := range []time.Duration{0, 0, time.Minute * 50} {
+		if wait := limiter.reserve(hour.Add(time.Minute * 10)); wait != expected {
+			t.Errorf("request %v: expected wait %v, got %v", i, expected, wait)
+		}
+	}
+
+	if wait := limiter.reserve(hour.Add(time.Hour)); wait != 0 {
+		t.Errorf("expected a new hour to reset the limit, got wait %v", wait)
+	}
+}
+
+func TestHourlyLimiterExhaustWithoutLimit(t *testing.T) {
+	limiter := &hourlyLimiter{}
+	limiter.Exhaust()
+
+	now := time.Now()
+	nextHour := now.Truncate(time.Hour).Add(time.Hour)
+
+	if wait := limiter.reserve(now); wait <= 0 || wait > time.Until(nextHour)+time.Second {
+		t.Errorf("expected a wait till the next hour, got %v", wait)
+	}
+
+	// no limit again in the next hour
+	for i := 0; i < 5; i++ {
+		if wait := limiter.reserve(nextHour.Add(time.Minute)); wait != 0 {
+			t.Fatalf("request %v of the next hour waits %v", i, wait)
+		}
+	}
+}
+
+func TestXmlFetchErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		code     int
+		expected errorx.Kind
+	}{
+		{"no results", xmlNotFound, ""},
+		{"invalid key", 42, errorx.KindBan},
+		{"ip not registered", 33, errorx.KindBan},
+		{"unknown error", 20, errorx.KindNetwork},
+	}
+
+	for _, test := range tests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, `<?xml version="1.0" encoding="utf-8"?><yandexsearch version="1.0"><response><error code="%v">error text</error></response></yandexsearch>`, test.code)
+		}))
+
+		resp, err := XmlEngine{}.Fetch(server.URL, nil, nil)
+		server.Close()
+
+		if kind := errorx.KindOf(err); kind != test.expected || (test.expected == "") != (err == nil) {
+			t.Errorf("%v: expected %q error, got %v", test.name, test.expected, err)
+		}
+
+		if (XmlEngine{}).IsBlocked(resp) {
+			t.Errorf("%v: blocked page", test.name)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+/**
+ * package selectors
+ *
+ * Versioned registry of SERP field selectors with ordered fallbacks. The first selector
+ * that matches wins, matches are counted so markup drift shows up in run stats.
+ */
+
+package selectors
+
+import (
+	"github.com/PuerkitoBio/goquery"
+)
+
+// NotMatched is the counter key for fields none of the selectors matched
+const NotMatched = "<none>"
+
+type Registry struct {
+	Version string
+	Fields  map[string][]string // field -> selectors in fallback order
+}
+
+// Matches counts matched selectors: field -> selector -> count
+type Matches map[string]map[string]int
+
+// Find returns the first non-empty selection of the field's fallback chain and counts the match
+func (r Registry) Find(node *goquery.Selection, field string, matches Matches) *goquery.Selection {
+	for _, selector := range r.Fields[field] {
+		found := node.Find(selector)
+
+		if found.Length() > 0 {
+			matches.Add(field, selector, 1)
+			return found
+		}
+	}
+
+	matches.Add(field, NotMatched, 1)
+
+	return node.Slice(0, 0)
+}
+
+// FindAll is Find for document level fields (e.g. the list of serp items)
+func (r Registry) FindAll(doc *goquery.Document, field string, matches Matches) *goquery.Selection {
+	return r.Find(doc.Selection, field, matches)
+}
+
+func (m Matches) Add(field string, selector string, count int) {
+	if m[field] == nil {
+		m[field] = map[string]int{}
+	}
+
+	m[field][selector] += count
+}
+
+func (m Matches) Merge(other Matches) {
+	for field, counts := range other {
+		for selector, count := range counts {
+			m.Add(field, selector, count)
+		}
+	}
+}
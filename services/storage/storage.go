@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"bufio"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -9,7 +10,7 @@ import (
 
 const storage_dir = "storage/"
 
-func WriteFile(name string, content any) {
+func WriteFile(name string, content any) error {
 	var data []byte
 	var err error
 
@@ -25,27 +26,106 @@ func WriteFile(name string, content any) {
 	default:
 		data, err = json.MarshalIndent(v, "", "  ")
 		if err != nil {
-			panic(fmt.Errorf("неизвестный тип и ошибка JSON-маршалинга: %w", err))
+			return fmt.Errorf("неизвестный тип и ошибка JSON-маршалинга: %w", err)
 		}
 	}
 
 	dir := filepath.Dir(storage_dir + name)
 	if err := os.MkdirAll(dir, 0755); err != nil {
-		panic(fmt.Errorf("ошибка создания папки: %w", err))
+		return fmt.Errorf("ошибка создания папки: %w", err)
 	}
 
 	err = os.WriteFile(storage_dir+name, data, 0644)
 	if err != nil {
-		panic(fmt.Errorf("ошибка записи файла: %w", err))
+		return fmt.Errorf("ошибка записи файла: %w", err)
 	}
+
+	return nil
 }
 
-func ReadFile(filename string) string {
+func ReadFile(filename string) (string, error) {
 	data, err := os.ReadFile(storage_dir + filename)
 
 	if err != nil {
-		panic(err)
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+// AppendJSONLine appends content as a single JSON line (JSON Lines format) and syncs the file,
+// so the line survives a crash of the process
+func AppendJSONLine(name string, content any) error {
+	data, err := json.Marshal(content)
+
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(storage_dir+name), 0755); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(storage_dir+name, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return err
+	}
+
+	return file.Sync()
+}
+
+// ReadJSONLines calls fn for every line of a JSON Lines file. Missing file is not an error,
+// a broken last line (process killed while writing) is skipped.
+func ReadJSONLines(name string, fn func(line []byte) error) error {
+	file, err := os.Open(storage_dir + name)
+
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+
+	for scanner.Scan() {
+		line := scanner.Bytes()
+
+		if len(line) == 0 || !json.Valid(line) {
+			continue
+		}
+
+		if err := fn(line); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+// Exists reports whether the storage file or directory exists
+func Exists(name string) bool {
+	_, err := os.Stat(storage_dir + name)
+
+	return err == nil
+}
+
+// Rename moves the storage file or directory
+func Rename(oldName string, newName string) error {
+	if err := os.MkdirAll(filepath.Dir(storage_dir+newName), 0755); err != nil {
+		return err
 	}
 
-	return string(data)
+	return os.Rename(storage_dir+oldName, storage_dir+newName)
 }
@@ -0,0 +1,243 @@
+/**
+ * package useragent
+ *
+ * Browser identity of a session. A FingerprintProfile is generated once per session from
+ * desktop Chrome presets and shared by Chrome (user agent override, client hints, screen,
+ * languages, timezone), CycleTLS (JA3, header order) and request headers, so every request
+ * of the session presents the same browser.
+ */
+
+package useragent
+
+import (
+	"fmt"
+	"math/rand"
+	"parser/services/config"
+	"strings"
+)
+
+// FingerprintProfile is the browser identity of a session
+type FingerprintProfile struct {
+	UserAgent         string `json:"user_agent"`
+	ChromeVersion     int    `json:"chrome_version"`     // major version, brands of the client hints are derived from it
+	Platform          string `json:"platform"`           // Sec-CH-UA-Platform: Windows, macOS, Linux
+	PlatformVersion   string `json:"platform_version"`   // Sec-CH-UA-Platform-Version
+	NavigatorPlatform string `json:"navigator_platform"` // navigator.platform
+	Architecture      string `json:"architecture"`
+	Mobile            bool   `json:"mobile"`
+
+	// TLS and HTTP/2 of CycleTLS. Pseudo-header order and SETTINGS follow the Chrome preset
+	// of CycleTLS (chosen by the user agent), headers are sent in HeaderOrder.
+	Ja3         string   `json:"ja3"`
+	HeaderOrder []string `json:"header_order"`
+
+	ScreenWidth  int      `json:"screen_width"`
+	ScreenHeight int      `json:"screen_height"`
+	Languages    []string `json:"languages"` // navigator.languages, the first one is the locale
+	Timezone     string   `json:"timezone"`
+}
+
+// Brand is an entry of Sec-CH-UA
+type Brand struct {
+	Brand   string
+	Version string
+}
+
+type platformPreset struct {
+	os                string // user agent platform token
+	platform          string
+	platformVersions  []string
+	navigatorPlatform string
+	architecture      string
+	screens           [][2]int
+	weight            int
+}
+
+var platformPresets = []platformPreset{
+	{
+		os:                "Windows NT 10.0; Win64; x64",
+		platform:          "Windows",
+		platformVersions:  []string{"10.0.0", "15.0.0", "19.0.0"},
+		navigatorPlatform: "Win32",
+		architecture:      "x86",
+		screens:           [][2]int{{1920, 1080}, {1366, 768}, {1536, 864}, {1600, 900}, {2560, 1440}},
+		weight:            7,
+	},
+	{
+		os:                "Macintosh; Intel Mac OS X 10_15_7",
+		platform:          "macOS",
+		platformVersions:  []string{"13.6.0", "14.6.1", "15.1.0"},
+		navigatorPlatform: "MacIntel",
+		architecture:      "arm",
+		screens:           [][2]int{{1440, 900}, {1512, 982}, {1728, 1117}, {1920, 1080}},
+		weight:            2,
+	},
+	{
+		os:                "X11; Linux x86_64",
+		platform:          "Linux",
+		platformVersions:  []string{"6.5.0", "6.8.0"},
+		navigatorPlatform: "Linux x86_64",
+		architecture:      "x86",
+		screens:           [][2]int{{1920, 1080}, {1366, 768}, {2560, 1440}},
+		weight:            1,
+	},
+}
+
+// chrome major versions of the generated profiles
+const (
+	minChromeVersion = 128
+	maxChromeVersion = 136
+)
+
+// ja3List are Chrome 128+ hellos: the same ciphers and curves, extensions are permuted
+// by Chrome itself
+var ja3List = []string{
+	"771,4865-4866-4867-49195-49199-49196-49200-52393-52392-49171-49172-156-157-47-53,11-5-51-65037-23-0-45-65281-27-13-18-35-16-43-10,4588-29-23-24,0",
+	"771,4865-4866-4867-49195-49199-49196-49200-52393-52392-49171-49172-156-157-47-53,16-65037-5-43-10-65281-35-18-51-0-23-45-11-27-13-41,4588-29-23-24,0",
+	"771,4865-4866-4867-49195-49199-49196-49200-52393-52392-49171-49172-156-157-47-53,51-10-43-65281-35-18-5-0-16-65037-13-45-11-23-27,4588-29-23-24,0",
+	"771,4865-4866-4867-49195-49199-49196-49200-52393-52392-49171-49172-156-157-47-53,23-51-16-5-65281-11-35-45-65037-10-13-43-27-0-18-41,4588-29-23-24,0",
+	"771,4865-4866-4867-49195-49199-49196-49200-52393-52392-49171-49172-156-157-47-53,5-45-35-65037-18-11-10-27-51-0-16-23-43-13-65281-41,4588-29-23-24,0",
+	"771,4865-4866-4867-49195-49199-49196-49200-52393-52392-49171-49172-156-157-47-53,18-16-27-23-51-45-43-65037-65281-5-10-11-13-35-0-41,4588-29-23-24,0",
+	"771,4865-4866-4867-49195-49199-49196-49200-52393-52392-49171-49172-156-157-47-53,35-51-45-13-5-0-11-27-23-65037-18-10-16-65281-43-41,4588-29-23-24,0",
+	"771,4865-4866-4867-49195-49199-49196-49200-52393-52392-49171-49172-156-157-47-53,35-45-43-16-23-27-5-51-65281-10-11-13-0-65037-18-41,4588-29-23-24,0",
+	"771,4865-4866-4867-49195-49199-49196-49200-52393-52392-49171-49172-156-157-47-53,23-43-35-45-51-11-18-10-13-65037-16-5-0-65281-27-41,4588-29-23-24,0",
+	"771,4865-4866-4867-49195-49199-49196-49200-52393-52392-49171-49172-156-157-47-53,35-45-10-23-11-5-51-65281-43-0-16-13-18-27-65037-41,4588-29-23-24,0",
+}
+
+// chromeHeaderOrder is the order Chrome sends navigation request headers in
+var chromeHeaderOrder = []string{
+	"cache-control",
+	"pragma",
+	"sec-ch-ua",
+	"sec-ch-ua-mobile",
+	"sec-ch-ua-platform",
+	"upgrade-insecure-requests",
+	"user-agent",
+	"accept",
+	"sec-fetch-site",
+	"sec-fetch-mode",
+	"sec-fetch-user",
+	"sec-fetch-dest",
+	"referer",
+	"accept-encoding",
+	"accept-language",
+	"cookie",
+	"priority",
+}
+
+// NewFingerprint generates a desktop Chrome profile with languages and timezone of the config
+func NewFingerprint() FingerprintProfile {
+	preset := randomPreset()
+	screen := preset.screens[rand.Intn(len(preset.screens))]
+	chromeVersion := minChromeVersion + rand.Intn(maxChromeVersion-minChromeVersion+1)
+
+	return FingerprintProfile{
+		UserAgent:         fmt.Sprintf("Mozilla/5.0 (%v) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/%v.0.0.0 Safari/537.36", preset.os, chromeVersion),
+		ChromeVersion:     chromeVersion,
+		Platform:          preset.platform,
+		PlatformVersion:   preset.platformVersions[rand.Intn(len(preset.platformVersions))],
+		NavigatorPlatform: preset.navigatorPlatform,
+		Architecture:      preset.architecture,
+		Ja3:               ja3List[rand.Intn(len(ja3List))],
+		HeaderOrder:       chromeHeaderOrder,
+		ScreenWidth:       screen[0],
+		ScreenHeight:      screen[1],
+		Languages:         strings.Split(config.FingerprintLanguages, ","),
+		Timezone:          config.FingerprintTimezone,
+	}
+}
+
+func randomPreset() platformPreset {
+	total := 0
+
+	for _, preset := range platformPresets {
+		total += preset.weight
+	}
+
+	n := rand.Intn(total)
+
+	for _, preset := range platformPresets {
+		if n < preset.weight {
+			return preset
+		}
+
+		n -= preset.weight
+	}
+
+	return platformPresets[0]
+}
+
+// Brands returns Sec-CH-UA brands of the Chrome version: Chromium, Google Chrome and
+// a GREASE brand, ordered and named by the version the way Chrome does
+func (p FingerprintProfile) Brands() []Brand {
+	greasyChars := []string{" ", "(", ":", "-", ".", "/", ")", ";", "=", "?", "_"}
+	greasedVersions := []string{"8", "99", "24"}
+	orders := [][3]int{{0, 1, 2}, {0, 2, 1}, {1, 0, 2}, {1, 2, 0}, {2, 0, 1}, {2, 1, 0}}
+
+	seed := p.ChromeVersion
+	version := fmt.Sprint(p.ChromeVersion)
+	order := orders[seed%len(orders)]
+
+	brands := make([]Brand, 3)
+	brands[order[0]] = Brand{
+		Brand:   "Not" + greasyChars[seed%len(greasyChars)] + "A" + greasyChars[(seed+1)%len(greasyChars)] + "Brand",
+		Version: greasedVersions[seed%len(greasedVersions)],
+	}
+	brands[order[1]] = Brand{Brand: "Chromium", Version: version}
+	brands[order[2]] = Brand{Brand: "Google Chrome", Version: version}
+
+	return brands
+}
+
+// SecChUa returns the Sec-CH-UA header value
+func (p FingerprintProfile) SecChUa() string {
+	list := []string{}
+
+	for _, brand := range p.Brands() {
+		list = append(list, fmt.Sprintf("%q;v=%q", brand.Brand, brand.Version))
+	}
+
+	return strings.Join(list, ", ")
+}
+
+// AcceptLanguage returns the Accept-Language header value of the languages: ru-RU,ru;q=0.9,...
+func (p FingerprintProfile) AcceptLanguage() string {
+	list := []string{}
+
+	for i, language := range p.Languages {
+		if i == 0 {
+			list = append(list, language)
+			continue
+		}
+
+		list = append(list, fmt.Sprintf("%v;q=%.1f", language, max(1-float64(i)/10, 0.1)))
+	}
+
+	return strings.Join(list, ",")
+}
+
+// Locale returns the first language
+func (p FingerprintProfile) Locale() string {
+	if len(p.Languages) == 0 {
+		return ""
+	}
+
+	return p.Languages[0]
+}
+
+// Headers returns the identity headers: User-Agent, client hints and Accept-Language
+func (p FingerprintProfile) Headers() map[string]string {
+	mobile := "?0"
+
+	if p.Mobile {
+		mobile = "?1"
+	}
+
+	return map[string]string{
+		"User-Agent":         p.UserAgent,
+		"Accept-Language":    p.AcceptLanguage(),
+		"sec-ch-ua":          p.SecChUa(),
+		"sec-ch-ua-mobile":   mobile,
+		"sec-ch-ua-platform": fmt.Sprintf("%q", p.Platform),
+	}
+}
@@ -0,0 +1,295 @@
+/**
+ * package warmup
+ *
+ * Session warm-up in the browser before the first search, ported from models/bot.go:
+ * main page visit, search page visit, static resources loading and suggest requests
+ * imitating typing. Steps of config.WarmupSteps are run in order, every step is counted
+ * with the captcha outcome of the first search, so the captcha rate of sessions warmed
+ * with a step can be compared to the "none" baseline.
+ */
+
+package warmup
+
+import (
+	"context"
+	"fmt"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+	"log"
+	"math/rand"
+	"net/url"
+	"parser/services/config"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Target describes the site to warm up on
+type Target struct {
+	MainUrl    string
+	SearchUrl  string
+	SuggestUrl string   // with %s for the typed part of the query
+	Resources  []string // static resources a browser loads from the site
+}
+
+// StepFunc runs a warm-up step in the browser context
+type StepFunc func(ctx context.Context, target Target, text string) error
+
+// step names
+const (
+	StepMainPage   = "main-page"
+	StepSearchPage = "search-page"
+	StepResources  = "resources"
+	StepSuggest    = "suggest"
+
+	// baseline of the sessions without succeeded steps
+	noSteps = "none"
+)
+
+var steps = map[string]StepFunc{
+	StepMainPage:   visitMainPage,
+	StepSearchPage: visitSearchPage,
+	StepResources:  loadResources,
+	StepSuggest:    typeSuggest,
+}
+
+// Run runs the config.WarmupSteps, each with config.WarmupStepProbability. Returns names
+// of the succeeded steps to be passed to RecordOutcome.
+func Run(ctx context.Context, target Target, text string) []string {
+	succeeded := []string{}
+
+	// the browser must be allocated by the session context, a browser started by the first
+	// step would be killed with its step timeout
+	if err := chromedp.Run(ctx); err != nil {
+		log.Printf("[WARN] Warm-up: %v", err)
+		return succeeded
+	}
+
+	for _, name := range strings.Split(config.WarmupSteps, ",") {
+		name = strings.TrimSpace(name)
+		step, ok := steps[name]
+
+		if name == "" || rand.Float64() >= config.WarmupStepProbability {
+			continue
+		}
+
+		startTime := time.Now()
+		var err error
+
+		if !ok {
+			err = fmt.Errorf("unknown step (available: %v)", strings.Join(stepNames(), ", "))
+		} else {
+			stepCtx, cancel := context.WithTimeout(ctx, config.WarmupStepTimeout)
+			err = step(stepCtx, target, text)
+			cancel()
+		}
+
+		recordStep(name, err, time.Since(startTime))
+
+		if err != nil {
+			log.Printf("[WARN] Warm-up step %v: %v", name, err)
+			continue
+		}
+
+		succeeded = append(succeeded, name)
+	}
+
+	return succeeded
+}
+
+func visitMainPage(ctx context.Context, target Target, text string) error {
+	log.Printf("[INFO] Warm-up: visit main page")
+
+	return chromedp.Run(ctx,
+		chromedp.Navigate(target.MainUrl),
+		chromedp.WaitReady("body"),
+		pause(time.Second*2, time.Second*5),
+	)
+}
+
+func visitSearchPage(ctx context.Context, target Target, text string) error {
+	log.Printf("[INFO] Warm-up: visit search page")
+
+	return chromedp.Run(ctx,
+		chromedp.Navigate(target.SearchUrl),
+		chromedp.WaitReady("body"),
+		pause(time.Second, time.Second*3),
+	)
+}
+
+// loadResources loads static resources from the page like a browser does
+func loadResources(ctx context.Context, target Target, text string) error {
+	if err := ensureOnSite(ctx, target); err != nil {
+		return err
+	}
+
+	for _, resource := range target.Resources {
+		if err := fetchFromPage(ctx, resource); err != nil {
+			return err
+		}
+
+		if err := chromedp.Run(ctx, pause(time.Millisecond*200, time.Millisecond*700)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// typeSuggest requests suggests of the growing first half of the query as typing does
+func typeSuggest(ctx context.Context, target Target, text string) error {
+	if err := ensureOnSite(ctx, target); err != nil {
+		return err
+	}
+
+	runes := []rune(text)
+
+	for i := 1; i <= len(runes)/2; i++ {
+		part := string(runes[:i])
+
+		if err := fetchFromPage(ctx, fmt.Sprintf(target.SuggestUrl, url.QueryEscape(part))); err != nil {
+			return err
+		}
+
+		if err := chromedp.Run(ctx, pause(time.Millisecond*150, time.Millisecond*400)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ensureOnSite opens the main page when the browser is not on the site yet (cookies, referer)
+func ensureOnSite(ctx context.Context, target Target) error {
+	var location string
+
+	if err := chromedp.Run(ctx, chromedp.Location(&location)); err != nil {
+		return err
+	}
+
+	mainUrl, err := url.Parse(target.MainUrl)
+
+	if err != nil {
+		return err
+	}
+
+	if current, err := url.Parse(location); err == nil && current.Host == mainUrl.Host {
+		return nil
+	}
+
+	return chromedp.Run(ctx, chromedp.Navigate(target.MainUrl), chromedp.WaitReady("body"))
+}
+
+// fetchFromPage requests the url from the page context with its cookies
+func fetchFromPage(ctx context.Context, resourceUrl string) error {
+	js := fmt.Sprintf(`fetch(%q, {mode: 'no-cors', credentials: 'include'}).then(() => true)`, resourceUrl)
+
+	return chromedp.Run(ctx, chromedp.Evaluate(js, nil, func(p *runtime.EvaluateParams) *runtime.EvaluateParams {
+		return p.WithAwaitPromise(true)
+	}))
+}
+
+// pause sleeps a random duration between min and max
+func pause(min, max time.Duration) chromedp.Action {
+	return chromedp.Sleep(min + time.Duration(rand.Int63n(int64(max-min)+1)))
+}
+
+// StepStats are counters of a warm-up step. Sessions and Captchas are first searches after
+// the step succeeded and the ones that got a captcha.
+type StepStats struct {
+	Runs        int     `json:"runs"`
+	Failures    int     `json:"failures"`
+	DurationMs  int64   `json:"duration_ms"`
+	Sessions    int     `json:"sessions"`
+	Captchas    int     `json:"captchas"`
+	CaptchaRate float64 `json:"captcha_rate"`
+}
+
+// Merge adds counters of other stats to s
+func (s *StepStats) Merge(other StepStats) {
+	s.Runs += other.Runs
+	s.Failures += other.Failures
+	s.DurationMs += other.DurationMs
+	s.Sessions += other.Sessions
+	s.Captchas += other.Captchas
+	s.CaptchaRate = 0
+
+	if s.Sessions > 0 {
+		s.CaptchaRate = float64(s.Captchas) / float64(s.Sessions)
+	}
+}
+
+var (
+	statsMu sync.Mutex
+	stats   = map[string]*StepStats{}
+)
+
+func stepStats(name string) *StepStats {
+	if stats[name] == nil {
+		stats[name] = &StepStats{}
+	}
+
+	return stats[name]
+}
+
+func recordStep(name string, err error, duration time.Duration) {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+
+	s := stepStats(name)
+	s.Runs++
+	s.DurationMs += duration.Milliseconds()
+
+	if err != nil {
+		s.Failures++
+	}
+}
+
+// RecordOutcome counts the first search of the session warmed with the succeeded steps
+func RecordOutcome(succeeded []string, captcha bool) {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+
+	if len(succeeded) == 0 {
+		succeeded = []string{noSteps}
+	}
+
+	for _, name := range succeeded {
+		outcome := StepStats{Sessions: 1}
+
+		if captcha {
+			outcome.Captchas = 1
+		}
+
+		stepStats(name).Merge(outcome)
+	}
+}
+
+// TakeStats returns the step counters gathered since the previous call and resets them
+func TakeStats() map[string]StepStats {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+
+	list := map[string]StepStats{}
+
+	for name, s := range stats {
+		list[name] = *s
+	}
+
+	stats = map[string]*StepStats{}
+
+	return list
+}
+
+func stepNames() []string {
+	list := []string{}
+
+	for name := range steps {
+		list = append(list, name)
+	}
+
+	sort.Strings(list)
+
+	return list
+}